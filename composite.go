@@ -0,0 +1,44 @@
+package indexedmap
+
+import "strings"
+
+// Separator of composite index key parts
+const compositeSep = "|"
+
+// Escapes separator and escape character itself, so different parts can't produce the same key.
+var compositeEscaper = strings.NewReplacer(`\`, `\\`, compositeSep, `\`+compositeSep)
+
+// Build composite index key from parts.
+// Parts are escaped and joined with "|", so "a|b" + "c" and "a" + "b|c" produce different keys.
+// Returns empty string, meaning element is not indexed, when all parts are empty.
+func CompositeKey(parts ...string) string {
+	empty := true
+	escaped := make([]string, len(parts))
+	for i, p := range parts {
+		if p != "" {
+			empty = false
+		}
+		escaped[i] = compositeEscaper.Replace(p)
+	}
+	if empty {
+		return ""
+	}
+	return strings.Join(escaped, compositeSep)
+}
+
+// Create index function combining values of several extractors into a composite key.
+// Use GetByComposite with values in the same order to query such index.
+func CompositeIndexFunc[T any](fns ...IndexFunc[T]) IndexFunc[T] {
+	return func(obj *T) string {
+		parts := make([]string, len(fns))
+		for i, fn := range fns {
+			parts[i] = fn(obj)
+		}
+		return CompositeKey(parts...)
+	}
+}
+
+// Find all elements by composite index values, given in the order of index extractors.
+func (r *IndexedMap[T]) GetByComposite(name string, values ...string) []T {
+	return r.GetByIndex(name, CompositeKey(values...))
+}
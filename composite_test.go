@@ -0,0 +1,40 @@
+package indexedmap
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompositeKey(t *testing.T) {
+	assert.NotEqual(t, CompositeKey("a|b", "c"), CompositeKey("a", "b|c"))
+	assert.NotEqual(t, CompositeKey(`a\`, "b"), CompositeKey("a", `\b`))
+	assert.NotEqual(t, CompositeKey("a", ""), CompositeKey("", "a"))
+	assert.Equal(t, "a|b", CompositeKey("a", "b"))
+	assert.Equal(t, "", CompositeKey("", ""))
+}
+
+func TestCompositeIndex(t *testing.T) {
+	m := NewIndexedMap(map[string]IndexFunc[Animal]{
+		"TypeRole": CompositeIndexFunc(
+			func(a *Animal) string {
+				return a.Type
+			},
+			func(a *Animal) string {
+				return a.Role
+			},
+		),
+	})
+
+	m.PutInt(1, Animal{Id: 1, Name: "Wolf", Type: "big", Role: "predator"})
+	m.PutInt(2, Animal{Id: 2, Name: "Bear", Type: "big", Role: "predator"})
+	m.PutInt(3, Animal{Id: 3, Name: "Cow", Type: "big", Role: "prey"})
+	m.PutInt(4, Animal{Id: 4, Name: "Odd", Type: "big|predator"})
+	m.PutInt(5, Animal{Id: 5, Name: "Ghost"})
+
+	assert.Equal(t, 2, len(m.GetByComposite("TypeRole", "Big", "Predator")))
+	assert.Equal(t, 1, len(m.GetByComposite("TypeRole", "big", "prey")))
+	assert.Equal(t, 1, len(m.GetByComposite("TypeRole", "big|predator", "")))
+	assert.Equal(t, 0, len(m.GetByComposite("TypeRole", "", "")))
+	assert.Equal(t, 3, len(m.GetIndexKeys("TypeRole")))
+}
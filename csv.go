@@ -0,0 +1,71 @@
+package indexedmap
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Write elements to w as CSV rows produced by encode, preceded by header row unless header is nil.
+// Elements are streamed one by one like in WriteJSON, in arbitrary order.
+// Primary keys are not written on their own, encode must put them into one of the columns
+// to read the data back with ReadCSV.
+func (r *IndexedMap[T]) WriteCSV(w io.Writer, header []string, encode func(T) []string) error {
+	cw := csv.NewWriter(w)
+	if header != nil {
+		if err := cw.Write(header); err != nil {
+			return err
+		}
+	}
+	var err error
+	r.ForEach(func(key string, value T) bool {
+		err = cw.Write(encode(value))
+		return err == nil
+	})
+	if err != nil {
+		return err
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// Read CSV rows from rd and put elements decoded from them, using keyCol column as primary key.
+// If hasHeader is true the first row is a header and is skipped, so data written by WriteCSV
+// is read back with hasHeader matching whether it was given a header.
+// All rows must have the same number of columns. Elements read before an error are kept in the map.
+// Returns ErrClosed if the map is closed.
+func (r *IndexedMap[T]) ReadCSV(rd io.Reader, hasHeader bool, keyCol int, decode func([]string) (T, error)) error {
+	if r.closed.Load() {
+		return ErrClosed
+	}
+	cr := csv.NewReader(rd)
+	if hasHeader {
+		if _, err := cr.Read(); err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+	}
+	for {
+		row, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		line, _ := cr.FieldPos(0)
+		if keyCol < 0 || keyCol >= len(row) {
+			return fmt.Errorf("indexedmap: CSV line %d has no key column %d", line, keyCol)
+		}
+		obj, err := decode(row)
+		if err != nil {
+			return fmt.Errorf("indexedmap: CSV line %d: %w", line, err)
+		}
+		if err := r.putLoaded(row[keyCol], obj); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package indexedmap
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func encodeAnimal(a Animal) []string {
+	return []string{strconv.Itoa(a.Id), a.Name, a.Type, a.Role}
+}
+
+func decodeAnimal(row []string) (Animal, error) {
+	id, err := strconv.Atoi(row[0])
+	return Animal{Id: id, Name: row[1], Type: row[2], Role: row[3]}, err
+}
+
+func TestWriteReadCSV(t *testing.T) {
+	m := NewAnimalMap()
+
+	m.PutInt(1, Animal{Id: 1, Name: "Cat, domestic", Type: "small", Role: "pet"})
+	m.PutInt(2, Animal{Id: 2, Name: "Cow", Type: "big", Role: "farm"})
+	m.PutInt(3, Animal{Id: 3, Name: "\"Dog\"", Type: "small", Role: "pet"})
+
+	var buf bytes.Buffer
+	assert.NoError(t, m.WriteCSV(&buf, []string{"Id", "Name", "Type", "Role"}, encodeAnimal))
+	assert.True(t, strings.HasPrefix(buf.String(), "Id,Name,Type,Role\n"))
+	assert.Equal(t, 4, strings.Count(buf.String(), "\n"))
+
+	n := NewAnimalMap()
+	assert.NoError(t, n.ReadCSV(&buf, true, 0, decodeAnimal))
+	assert.Equal(t, 3, n.Size())
+	a, ok := n.GetInt(1)
+	assert.True(t, ok)
+	assert.Equal(t, "Cat, domestic", a.Name)
+	assert.ElementsMatch(t, m.GetByIndex("Type", "small"), n.GetByIndex("Type", "small"))
+
+	e := NewAnimalMap()
+	assert.NoError(t, e.ReadCSV(strings.NewReader(""), true, 0, decodeAnimal))
+	assert.NoError(t, e.ReadCSV(strings.NewReader("Id,Name,Type,Role\n"), true, 0, decodeAnimal))
+	assert.True(t, e.IsEmpty())
+
+	err := e.ReadCSV(strings.NewReader("Id,Name,Type,Role\n1,Cat,small,pet\nx,Cow,big,farm\n"), true, 0, decodeAnimal)
+	assert.ErrorIs(t, err, strconv.ErrSyntax)
+	assert.Contains(t, err.Error(), "line 3")
+	assert.Equal(t, 1, e.Size())
+	assert.Error(t, e.ReadCSV(strings.NewReader("Id,Name,Type,Role\n1,Cat,small\n"), true, 0, decodeAnimal))
+	assert.Error(t, e.ReadCSV(strings.NewReader("Id,Name,Type,Role\n1,Cat,small,pet\n"), true, 4, decodeAnimal))
+
+	buf.Reset()
+	assert.NoError(t, m.WriteCSV(&buf, nil, encodeAnimal))
+	assert.Equal(t, 3, strings.Count(buf.String(), "\n"))
+	h := NewAnimalMap()
+	assert.NoError(t, h.ReadCSV(&buf, false, 0, decodeAnimal))
+	assert.Equal(t, 3, h.Size())
+	assert.ElementsMatch(t, m.Values(), h.Values())
+	assert.NoError(t, h.ReadCSV(strings.NewReader(""), false, 0, decodeAnimal))
+	assert.Error(t, m.WriteCSV(failingWriter{}, nil, encodeAnimal))
+}
@@ -0,0 +1,63 @@
+package indexedmap
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"github.com/puzpuzpuz/xsync/v3"
+)
+
+// Number of keys per index value listed by DebugString
+const debugMaxPerValue = 10
+
+// Write human readable listing of map structure to w for troubleshooting:
+// number of primary keys and, for each secondary index, its values with primary keys of their elements.
+// Indexes, values and keys are sorted. At most maxPerValue keys are listed per value, 0 lists all of them.
+// It's a diagnostic aid, the format is not stable and is not meant to be parsed.
+func (r *IndexedMap[T]) Dump(w io.Writer, maxPerValue int) error {
+	t := r.mu.RLock()
+	defer r.mu.RUnlock(t)
+	if _, err := fmt.Fprintf(w, "primary: %d keys\n", r.Size()); err != nil {
+		return err
+	}
+	for _, name := range r.indexNames() {
+		values := r.indexKeys(name)
+		slices.Sort(values)
+		if _, err := fmt.Fprintf(w, "index %s: %d values\n", name, len(values)); err != nil {
+			return err
+		}
+		for _, value := range values {
+			m, ok := r.findIndexMapList(name, value)
+			if !ok {
+				continue
+			}
+			if _, err := fmt.Fprintf(w, "  %s: %s\n", value, debugKeys(m, maxPerValue)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Get listing of map structure, see Dump. At most 10 keys are listed per index value.
+func (r *IndexedMap[T]) DebugString() string {
+	var b strings.Builder
+	r.Dump(&b, debugMaxPerValue)
+	return b.String()
+}
+
+// Format sorted keys of index value collection, truncated to limit keys if it's positive.
+func debugKeys(m *xsync.Map, limit int) string {
+	keys := []string{}
+	m.Range(func(k string, _ any) bool {
+		keys = append(keys, k)
+		return true
+	})
+	slices.Sort(keys)
+	if limit <= 0 || len(keys) <= limit {
+		return strings.Join(keys, ", ")
+	}
+	return fmt.Sprintf("%s, ... (%d more)", strings.Join(keys[:limit], ", "), len(keys)-limit)
+}
@@ -0,0 +1,40 @@
+package indexedmap
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type failingWriter struct{}
+
+func (failingWriter) Write(p []byte) (int, error) {
+	return 0, errors.New("write failed")
+}
+
+func TestDebugString(t *testing.T) {
+	m := NewTaggedPersonMap()
+
+	m.Put("alex", Person{Id: 1, LastName: "Smith", Tags: []string{"dev", "ops"}})
+	m.Put("john", Person{Id: 2, LastName: "Doe", Tags: []string{"dev"}})
+
+	assert.Equal(t, "primary: 2 keys\n"+
+		"index LastName: 2 values\n"+
+		"  DOE: JOHN\n"+
+		"  SMITH: ALEX\n"+
+		"index Tag: 2 values\n"+
+		"  DEV: ALEX, JOHN\n"+
+		"  OPS: ALEX\n", m.DebugString())
+
+	for i := range 15 {
+		m.PutInt(100+i, Person{Id: 100 + i, LastName: "Doe"})
+	}
+	assert.Contains(t, m.DebugString(), "  DOE: 100, 101, 102, 103, 104, 105, 106, 107, 108, 109, ... (6 more)\n")
+
+	var b strings.Builder
+	assert.NoError(t, m.Dump(&b, 0))
+	assert.Contains(t, b.String(), "113, 114, JOHN\n")
+	assert.Error(t, m.Dump(failingWriter{}, 0))
+}
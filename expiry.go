@@ -0,0 +1,114 @@
+package indexedmap
+
+import (
+	"container/heap"
+	"sync"
+	"sync/atomic"
+
+	"github.com/puzpuzpuz/xsync/v3"
+)
+
+// States of expiry entry
+const (
+	// deadline is not reached or not noticed yet
+	expiryPending int32 = iota
+	// deadline is reached and entry is included in expired count
+	expiryCounted
+	// entry is replaced or deleted, it's not counted anymore
+	expiryRetired
+)
+
+// Expiration deadline of element put with TTL.
+type expiryEntry struct {
+	key      string
+	deadline int64
+	state    atomic.Int32
+}
+
+// Expiration deadlines by primary key, created on first PutWithTTL.
+// Deadlines are also queued in order, so elements which expired but are not removed yet
+// are counted as their deadlines pass instead of ranging all of them on every Size.
+// Queue keeps entries replaced or deleted before their deadline until the deadline passes.
+type expiryIndex struct {
+	// *expiryEntry by primary key
+	entries *xsync.Map
+
+	mu    sync.Mutex
+	queue expiryQueue
+
+	// Number of current entries past deadline, updated when they are counted and when they are replaced
+	expired atomic.Int64
+}
+
+func newExpiryIndex() *expiryIndex {
+	return &expiryIndex{entries: xsync.NewMap()}
+}
+
+// Set or reset deadline of key, must be called under primary key lock.
+func (e *expiryIndex) set(key string, deadline int64) {
+	var prev any
+	var ok bool
+	if deadline > 0 {
+		entry := &expiryEntry{key: key, deadline: deadline}
+		prev, ok = e.entries.LoadAndStore(key, entry)
+		e.mu.Lock()
+		heap.Push(&e.queue, entry)
+		e.mu.Unlock()
+	} else {
+		prev, ok = e.entries.LoadAndDelete(key)
+	}
+	if ok && prev.(*expiryEntry).state.Swap(expiryRetired) == expiryCounted {
+		e.expired.Add(-1)
+	}
+}
+
+// Get deadline of key, 0 if it never expires.
+func (e *expiryIndex) deadline(key string) int64 {
+	if v, ok := e.entries.Load(key); ok {
+		return v.(*expiryEntry).deadline
+	}
+	return 0
+}
+
+// Count current entries with deadline not after now, it's O(1) amortized:
+// each entry is taken from the queue once when its deadline passes.
+func (e *expiryIndex) expiredCount(now int64) int {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for len(e.queue) > 0 && e.queue[0].deadline <= now {
+		entry := heap.Pop(&e.queue).(*expiryEntry)
+		if entry.state.CompareAndSwap(expiryPending, expiryCounted) {
+			e.expired.Add(1)
+		}
+	}
+	return int(e.expired.Load())
+}
+
+// Remove all deadlines, must be called under exclusive map lock.
+func (e *expiryIndex) clear() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.entries.Clear()
+	e.queue = nil
+	e.expired.Store(0)
+}
+
+// Expiry entries ordered by deadline, see container/heap.
+type expiryQueue []*expiryEntry
+
+func (q expiryQueue) Len() int           { return len(q) }
+func (q expiryQueue) Less(i, j int) bool { return q[i].deadline < q[j].deadline }
+func (q expiryQueue) Swap(i, j int)      { q[i], q[j] = q[j], q[i] }
+
+func (q *expiryQueue) Push(x any) {
+	*q = append(*q, x.(*expiryEntry))
+}
+
+func (q *expiryQueue) Pop() any {
+	old := *q
+	n := len(old)
+	entry := old[n-1]
+	old[n-1] = nil
+	*q = old[:n-1]
+	return entry
+}
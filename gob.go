@@ -0,0 +1,72 @@
+package indexedmap
+
+import (
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+)
+
+// Snapshot header, written before elements.
+type gobHeader struct {
+	Indexes []string
+}
+
+// Single snapshot element.
+type gobEntry[T any] struct {
+	Key   string
+	Value T
+}
+
+// Write map snapshot to w in gob format.
+// Elements are streamed one by one, so the whole map is never buffered.
+// Secondary indexes are not written, only names of them to validate configuration on Load.
+func (r *IndexedMap[T]) Save(w io.Writer) error {
+	t := r.mu.RLock()
+	names := r.indexNames()
+	r.mu.RUnlock(t)
+	enc := gob.NewEncoder(w)
+	if err := enc.Encode(gobHeader{Indexes: names}); err != nil {
+		return err
+	}
+	var err error
+	r.ForEach(func(key string, value T) bool {
+		err = enc.Encode(gobEntry[T]{Key: key, Value: value})
+		return err == nil
+	})
+	return err
+}
+
+// Read map snapshot written by Save from r and put all elements into the map.
+// Secondary indexes are rebuilt with configured index functions,
+// so the map must have the same index names as the one used at Save.
+// Returns ErrClosed if the map is closed.
+func (r *IndexedMap[T]) Load(rd io.Reader) error {
+	if r.closed.Load() {
+		return ErrClosed
+	}
+	dec := gob.NewDecoder(rd)
+	var header gobHeader
+	if err := dec.Decode(&header); err != nil {
+		return err
+	}
+	t := r.mu.RLock()
+	names := r.indexNames()
+	r.mu.RUnlock(t)
+	if !slices.Equal(header.Indexes, names) {
+		return fmt.Errorf("indexedmap: snapshot indexes %v don't match map indexes %v", header.Indexes, names)
+	}
+	for {
+		var entry gobEntry[T]
+		if err := dec.Decode(&entry); err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+		if err := r.putLoaded(entry.Key, entry.Value); err != nil {
+			return err
+		}
+	}
+}
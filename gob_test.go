@@ -0,0 +1,51 @@
+package indexedmap
+
+import (
+	"bytes"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSaveLoad(t *testing.T) {
+	m := NewAnimalMap()
+
+	for i := range 1000 {
+		tp := "big"
+		if i%2 == 0 {
+			tp = "small"
+		}
+		m.PutInt(i, Animal{Id: i, Name: "animal" + strconv.Itoa(i), Type: tp, NumType: i % 7})
+	}
+
+	var buf bytes.Buffer
+	assert.NoError(t, m.Save(&buf))
+
+	n := NewAnimalMap()
+	assert.NoError(t, n.Load(&buf))
+
+	assert.Equal(t, m.Size(), n.Size())
+	assert.ElementsMatch(t, m.GetByIndex("Type", "small"), n.GetByIndex("Type", "small"))
+	assert.ElementsMatch(t, m.GetByIndex("NumType", "3"), n.GetByIndex("NumType", "3"))
+
+	a, ok := n.GetInt(42)
+	assert.True(t, ok)
+	assert.Equal(t, "animal42", a.Name)
+}
+
+func TestLoadIndexMismatch(t *testing.T) {
+	m := NewAnimalMap()
+	m.PutInt(1, Animal{Id: 1, Name: "Cat"})
+
+	var buf bytes.Buffer
+	assert.NoError(t, m.Save(&buf))
+
+	n := NewIndexedMap(map[string]IndexFunc[Animal]{
+		"Type": func(a *Animal) string {
+			return a.Type
+		},
+	})
+	assert.Error(t, n.Load(&buf))
+	assert.True(t, n.IsEmpty())
+}
@@ -1,16 +1,41 @@
 package indexedmap
 
 import (
+	"errors"
+	"fmt"
+	"maps"
 	"runtime"
+	"slices"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 
-	"github.com/puzpuzpuz/xsync"
+	"github.com/puzpuzpuz/xsync/v3"
 )
 
-// Index extraction function type
+// Returned when operation refers to index which is not registered in the map.
+var ErrIndexNotFound = errors.New("indexedmap: index not found")
+
+// Returned when element with primary key is not present in the map.
+var ErrKeyNotFound = errors.New("indexedmap: key not found")
+
+// Returned or used as panic value by writes to the map after Close.
+var ErrClosed = errors.New("indexedmap: map is closed")
+
+// Index extraction function type.
+// Empty value means element is not indexed.
 type IndexFunc[T any] func(obj *T) string
 
+// Index extraction function type which decides whether element is indexed separately from its value.
+// Element is not indexed when it returns false, while true with empty value is the same as empty IndexFunc value.
+type IndexFuncOpt[T any] func(obj *T) (string, bool)
+
+// Multi-value index extraction function type.
+// Element is indexed under each of returned values, empty slice means element is not indexed.
+type IndexFuncMulti[T any] func(obj *T) []string
+
 // Map data structure with seconday indexes.
 // Stores pointers to elements of type T.
 // Indexes are limited to string type only.
@@ -19,79 +44,637 @@ type IndexedMap[T any] struct {
 	// Primary key index
 	primary *xsync.Map
 
+	// Keys as passed by callers by primary key, only for keys changed by normalization
+	origKeys *xsync.Map
+
 	// Secondary indexes by name.
-	// Each secondary index is a map of index values and collections of objects
-	// having this index value stored as map[K]*T
+	// Each secondary index is a map of index values and collections of primary keys
+	// of objects having this index value stored as map[K]struct{}
 	secondary map[string]*xsync.Map
 
-	// indexes configuration via map of index names and extraction functions
-	indexes map[string]IndexFunc[T]
+	// indexes configuration via map of index names and extraction functions.
+	// Single-value functions are wrapped to return one value.
+	indexes map[string]IndexFuncMulti[T]
+
+	// Guards multi-map operations against whole-map changes like Clear.
+	// Regular reads and writes take the reader side, so they don't block each other.
+	mu *xsync.RBMutex
+
+	// Number of elements in primary index.
+	// Maintained by writers, so Size doesn't need to range the whole map.
+	size atomic.Int64
+
+	// Counters of element writes and removals reported by Metrics
+	puts    atomic.Uint64
+	removes atomic.Uint64
+
+	// Normalization applied to primary keys and index values
+	normalize func(string) string
+
+	// Names of unique indexes and lock serializing writers checking them
+	unique   []string
+	uniqueMu sync.Mutex
+
+	// Sorted values of ordered indexes by index name
+	ordered map[string]*sortedValues
+
+	// Access order of elements, only for maps with MaxSize option
+	lru *lruList
+
+	// Construction options, used to create maps with the same configuration
+	opts Options[T]
+
+	// Expiration deadlines in unix nanoseconds by primary key.
+	// Created on first PutWithTTL, so maps without TTL don't pay for expiration checks.
+	expiry atomic.Pointer[expiryIndex]
+
+	// Change listeners, replaced as a whole on registration
+	listeners   atomic.Pointer[[]*listener[T]]
+	listenersMu sync.Mutex
+
+	// Closed to stop background sweeper, nil when sweeper is not running
+	stopSweeper chan struct{}
+	sweeperDone chan struct{}
+	sweeperMu   sync.Mutex
+
+	// Set by Close, writes to closed map are rejected
+	closed atomic.Bool
+}
+
+// IndexedMap construction options.
+type Options[T any] struct {
+
+	// Multi-value indexes registered in addition to single-value ones.
+	// Index names must be unique across both, multi-value index wins on collision.
+	MultiIndexes map[string]IndexFuncMulti[T]
+
+	// Indexes which may exclude elements, registered in addition to single and multi-value ones.
+	// Index names must be unique across all of them, multi-value index wins on collision.
+	OptIndexes map[string]IndexFuncOpt[T]
+
+	// Keep primary keys and index values as is instead of converting them to upper case.
+	// Mixing case-sensitive and case-insensitive maps on the same data set is not supported,
+	// as keys stored in one mode can't be found by lookups made in another.
+	CaseSensitive bool
+
+	// Function normalizing primary keys and index values, overrides CaseSensitive when set.
+	// The same normalizer is applied to stored and looked up keys and values, so it must be deterministic.
+	KeyNormalizer func(string) string
+
+	// Names of indexes which don't allow the same value for elements with different keys.
+	// Writes violating them are rejected, see PutErr.
+	UniqueIndexes []string
+
+	// Names of indexes which additionally keep their values sorted,
+	// enabling range queries and sorted value listing.
+	OrderedIndexes []string
+
+	// Maximum number of elements, 0 means the map is unbounded.
+	// When a put makes the map larger, the least recently used elements are removed
+	// from primary and secondary indexes like with Remove. Get and Put count as use.
+	// Access order is kept in a list guarded by single mutex, which adds contention to reads.
+	MaxSize int
+
+	// Equality of elements. When set, Put of element equal to the stored one
+	// keeps the stored element and skips computing index functions and updating secondary indexes.
+	// Change listeners are still notified about such put.
+	Equals func(a, b T) bool
+
+	// Pool limiting goroutines of parallel operations, e.g. one pool shared by many maps.
+	// Maps without pool share the default one sized to the number of CPUs.
+	WorkerPool *WorkerPool
 }
 
 // Create new IndexedMap instance.
 func NewIndexedMap[T any](indexes map[string]IndexFunc[T]) *IndexedMap[T] {
+	return NewIndexedMapMulti(indexes, nil)
+}
+
+// Create new IndexedMap instance with primary index presized for sizeHint elements,
+// so bulk loads of that many elements don't grow it step by step.
+// Secondary indexes are not presized: they hold an entry per index value, usually far fewer than elements.
+func NewIndexedMapSized[T any](indexes map[string]IndexFunc[T], sizeHint int) *IndexedMap[T] {
+	r := NewIndexedMap(indexes)
+	r.primary = xsync.NewMapPresized(sizeHint)
+	return r
+}
+
+// Create new IndexedMap instance with both single and multi-value indexes.
+// Index names must be unique across both maps, multi-value index wins on collision.
+func NewIndexedMapMulti[T any](indexes map[string]IndexFunc[T], multi map[string]IndexFuncMulti[T]) *IndexedMap[T] {
+	return NewIndexedMapWithOptions(indexes, Options[T]{MultiIndexes: multi})
+}
+
+// Create new IndexedMap instance with construction options.
+func NewIndexedMapWithOptions[T any](indexes map[string]IndexFunc[T], opts Options[T]) *IndexedMap[T] {
+	all := map[string]IndexFuncMulti[T]{}
+	for name, fn := range indexes {
+		all[name] = singleValue(fn)
+	}
+	for name, fn := range opts.OptIndexes {
+		all[name] = optValue(fn)
+	}
+	for name, fn := range opts.MultiIndexes {
+		all[name] = fn
+	}
+	opts.MultiIndexes = nil
+	opts.OptIndexes = nil
+	return newIndexedMap(all, opts)
+}
+
+func newIndexedMap[T any](indexes map[string]IndexFuncMulti[T], opts Options[T]) *IndexedMap[T] {
 	r := IndexedMap[T]{
 		primary:   xsync.NewMap(),
+		origKeys:  xsync.NewMap(),
 		secondary: map[string]*xsync.Map{},
 		indexes:   indexes,
+		mu:        xsync.NewRBMutex(),
+		normalize: strings.ToUpper,
+		ordered:   map[string]*sortedValues{},
+		opts:      opts,
+	}
+	if opts.CaseSensitive {
+		r.normalize = func(s string) string { return s }
+	}
+	if opts.KeyNormalizer != nil {
+		r.normalize = opts.KeyNormalizer
 	}
 	for name := range indexes {
 		r.secondary[name] = xsync.NewMap()
 	}
+	for _, name := range opts.UniqueIndexes {
+		if _, ok := indexes[name]; ok && !slices.Contains(r.unique, name) {
+			r.unique = append(r.unique, name)
+		}
+	}
+	for _, name := range opts.OrderedIndexes {
+		if _, ok := indexes[name]; ok {
+			r.ordered[name] = newSortedValues()
+		}
+	}
+	if opts.MaxSize > 0 {
+		r.lru = newLRUList()
+	}
 	return &r
 }
 
+func singleValue[T any](fn IndexFunc[T]) IndexFuncMulti[T] {
+	return func(obj *T) []string {
+		return []string{fn(obj)}
+	}
+}
+
+func optValue[T any](fn IndexFuncOpt[T]) IndexFuncMulti[T] {
+	return func(obj *T) []string {
+		if v, ok := fn(obj); ok {
+			return []string{v}
+		}
+		return nil
+	}
+}
+
+// Convert int key to string primary key.
+// Decimal digits are not changed by case normalization, so int keys skip it unless custom normalizer is set.
+func (r *IndexedMap[T]) intKey(key int) string {
+	if r.opts.KeyNormalizer != nil {
+		return r.normalize(strconv.Itoa(key))
+	}
+	return strconv.Itoa(key)
+}
+
+// Convert element to primary index value.
+func (r *IndexedMap[T]) box(obj *T) any {
+	return *obj
+}
+
+// Get copy of element from primary index value.
+func (r *IndexedMap[T]) unbox(v any) T {
+	return v.(T)
+}
+
+// Get pointer to copy of element from primary index value, e.g. to pass it to index functions.
+func (r *IndexedMap[T]) pointer(v any) *T {
+	obj := v.(T)
+	return &obj
+}
+
+// Remember key as passed by caller, must be called under primary key lock.
+func (r *IndexedMap[T]) setOrigKey(key string, orig string) {
+	if orig != key {
+		r.origKeys.Store(key, orig)
+	} else {
+		r.origKeys.Delete(key)
+	}
+}
+
+// Get key of element as it was passed by caller on last put.
+func (r *IndexedMap[T]) originalKey(key string) string {
+	if orig, ok := r.origKeys.Load(key); ok {
+		return orig.(string)
+	}
+	return key
+}
+
+// Run fn holding reader side of map lock.
+// The lock is released even if fn panics, so the map stays usable after the panic is recovered.
+func (r *IndexedMap[T]) withRLock(fn func()) {
+	t := r.mu.RLock()
+	defer r.mu.RUnlock(t)
+	fn()
+}
+
+// Run fn holding exclusive map lock, see withRLock.
+func (r *IndexedMap[T]) withLock(fn func()) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	fn()
+}
+
 // Add element to map using primary key of type int.
 // Internally primary key is converted to string.
 // This method has eventual consistency for primary and secondary indexes update.
 // Primary index is updated after secondary.
 func (r *IndexedMap[T]) PutInt(key int, obj T) {
-	r.Put(strconv.Itoa(key), obj)
+	r.checkOpen()
+	k := r.intKey(key)
+	r.putKey(k, k, obj)
 }
 
 // Add element to map by primary key.
 // This method has eventual consistency for primary and secondary indexes update.
 // Primary index is updated after secondary.
+// Element violating unique index is not stored, use PutErr to detect it.
 func (r *IndexedMap[T]) Put(k string, obj T) {
-	key := strings.ToUpper(k)
-	if _, ok := r.Get(key); ok {
-		for index := range r.indexes {
-			r.updateIndex(index, &obj, key)
+	r.checkOpen()
+	r.putKey(r.normalize(k), k, obj)
+}
+
+// Put element read by loaders like ReadJSON, which return ErrClosed instead of panic if the map is closed.
+// Element violating unique index is not stored like in Put.
+func (r *IndexedMap[T]) putLoaded(k string, obj T) error {
+	if r.closed.Load() {
+		return ErrClosed
+	}
+	r.putKey(r.normalize(k), k, obj)
+	return nil
+}
+
+// Put element and get previous one in single step.
+// Secondary indexes are updated exactly like in Put, returns previous element and true if key was present.
+// Like in Put, element violating unique index is not stored, and current element is returned then.
+func (r *IndexedMap[T]) Swap(k string, obj T) (T, bool) {
+	return r.PutReturning(k, obj)
+}
+
+// Add element to map by primary key and get element it replaced, e.g. for audit logs.
+// Returns previous element and true if key was present.
+func (r *IndexedMap[T]) PutReturning(k string, obj T) (T, bool) {
+	r.checkOpen()
+	prev, loaded, _ := r.putKey(r.normalize(k), k, obj)
+	return prev, loaded
+}
+
+// Add element to map using primary key of type int and get element it replaced, see PutReturning.
+func (r *IndexedMap[T]) PutIntReturning(key int, obj T) (T, bool) {
+	r.checkOpen()
+	k := r.intKey(key)
+	prev, loaded, _ := r.putKey(k, k, obj)
+	return prev, loaded
+}
+
+// Put element by already normalized key, orig is the key as passed by caller.
+// Callers check that the map is open before.
+func (r *IndexedMap[T]) putKey(key string, orig string, obj T) (T, bool, error) {
+	var prev T
+	var loaded bool
+	var dropped []ChangeEvent[T]
+	var err error
+	r.withRLock(func() {
+		prev, loaded, dropped, err = r.put(key, orig, obj)
+	})
+	r.notify(dropped...)
+	if err == nil {
+		r.notifyPut(key, prev, obj, loaded)
+		r.evict()
+	}
+	return prev, loaded, err
+}
+
+// Secondary indexes are updated under primary key lock,
+// so concurrent writers of the same key can't interleave their index changes.
+// Returns previous element and true if key was present,
+// or ErrUniqueViolation if element wasn't stored because of unique index.
+// Expired element is replaced like absent one, removal of it is returned as event, see compute.
+func (r *IndexedMap[T]) put(key string, orig string, obj T) (T, bool, []ChangeEvent[T], error) {
+	return r.putExpiring(key, orig, obj, 0)
+}
+
+// Put element which expires at deadline in unix nanoseconds, 0 means element never expires.
+func (r *IndexedMap[T]) putExpiring(key string, orig string, obj T, deadline int64) (T, bool, []ChangeEvent[T], error) {
+	var prev T
+	var found bool
+	var err error
+	dropped := r.compute(key, func(old any, loaded bool) (any, bool) {
+		if loaded {
+			prev, found = r.unbox(old), true
+			if r.opts.Equals != nil && r.opts.Equals(prev, obj) {
+				// unchanged element keeps its index values, only bookkeeping of the put is needed
+				r.setExpiry(key, deadline)
+				r.setOrigKey(key, orig)
+				r.touch(key)
+				r.puts.Add(1)
+				return old, false
+			}
 		}
-	} else {
-		for index := range r.indexes {
-			r.putToIndex(index, strings.ToUpper(r.indexes[index](&obj)), &obj, key)
+		if err = r.lockUnique(key, &obj); err != nil {
+			return old, !loaded
+		}
+		defer r.unlockUnique()
+		r.setExpiry(key, deadline)
+		if loaded {
+			for index := range r.indexes {
+				r.updateIndex(index, &obj, &prev, key)
+			}
+			r.setOrigKey(key, orig)
+			r.touch(key)
+			r.puts.Add(1)
+		} else {
+			r.insert(key, orig, &obj)
+		}
+		return r.box(&obj), false
+	})
+	return prev, found, dropped, err
+}
+
+// Index new element and count it. Must be called under primary key lock.
+func (r *IndexedMap[T]) insert(key string, orig string, obj *T) {
+	r.setOrigKey(key, orig)
+	if r.lru != nil {
+		r.lru.add(key)
+	}
+	for index := range r.indexes {
+		for _, v := range r.indexValues(index, obj) {
+			r.putToIndex(index, v, key)
+		}
+	}
+	r.size.Add(1)
+	r.puts.Add(1)
+}
+
+// Add element to map only if primary key is not present yet.
+// Returns stored element and false if it was added, or existing element and true otherwise.
+// Secondary indexes are not touched when key already exists.
+// Expired element counts as absent: it's removed and replaced.
+// Element violating unique index is not added, and zero value and true are returned then,
+// use PutIfAbsentErr to tell it from existing element.
+func (r *IndexedMap[T]) PutIfAbsent(k string, obj T) (T, bool) {
+	r.checkOpen()
+	actual, loaded, err := r.putIfAbsent(k, obj)
+	return actual, loaded || err != nil
+}
+
+// Put element if key is absent, returns zero value and ErrUniqueViolation if it's rejected by unique index.
+func (r *IndexedMap[T]) putIfAbsent(k string, obj T) (T, bool, error) {
+	key := r.normalize(k)
+	var actual any
+	var loaded bool
+	var dropped []ChangeEvent[T]
+	var err error
+	r.withRLock(func() {
+		actual, loaded, dropped = r.loadOrCompute(key, func() (any, bool) {
+			var v any
+			v, err = r.insertUnique(key, k, &obj)
+			return v, err == nil
+		})
+	})
+	r.notify(dropped...)
+	if err != nil {
+		var zero T
+		return zero, false, err
+	}
+	if !loaded {
+		r.notify(ChangeEvent[T]{Op: OpInsert, Key: key, New: obj})
+		r.evict()
+	}
+	return r.unbox(actual), loaded, nil
+}
+
+// Get element by primary key or create it with factory if key is not present.
+// Returns the element and true if it was created by this call.
+// Factory is called at most once per missing key, even under concurrent calls.
+// It runs under primary key lock and must not call methods of the map.
+// Expired element counts as absent like in PutIfAbsent.
+// Created element violating unique index is not added, and zero value and false are returned then.
+func (r *IndexedMap[T]) ComputeIfAbsent(k string, factory func() T) (T, bool) {
+	r.checkOpen()
+	key := r.normalize(k)
+	var actual any
+	var loaded bool
+	var dropped []ChangeEvent[T]
+	r.withRLock(func() {
+		actual, loaded, dropped = r.loadOrCompute(key, func() (any, bool) {
+			obj := factory()
+			v, err := r.insertUnique(key, k, &obj)
+			return v, err == nil
+		})
+	})
+	r.notify(dropped...)
+	if actual == nil {
+		var zero T
+		return zero, false
+	}
+	if !loaded {
+		r.notify(ChangeEvent[T]{Op: OpInsert, Key: key, New: r.unbox(actual)})
+		r.evict()
+	}
+	return r.unbox(actual), !loaded
+}
+
+// Change element in place by primary key.
+// Mutate gets pointer to a copy of stored element, and if it returns true
+// the copy is stored back and secondary indexes are updated like in Put.
+// Returns true if element was updated, false if key is missing or expired, mutate declined the change
+// or changed element violates unique index.
+// Mutate runs under primary key lock and must not call methods of the map.
+func (r *IndexedMap[T]) Update(k string, mutate func(*T) bool) bool {
+	r.checkOpen()
+	key := r.normalize(k)
+	var prev, obj T
+	updated := false
+	var dropped []ChangeEvent[T]
+	r.withRLock(func() {
+		dropped = r.compute(key, func(old any, loaded bool) (any, bool) {
+			if !loaded {
+				return old, true
+			}
+			prev = r.unbox(old)
+			obj = prev
+			if !mutate(&obj) || r.lockUnique(key, &obj) != nil {
+				return old, false
+			}
+			defer r.unlockUnique()
+			for index := range r.indexes {
+				r.updateIndex(index, &obj, &prev, key)
+			}
+			updated = true
+			r.touch(key)
+			r.puts.Add(1)
+			return r.box(&obj), false
+		})
+	})
+	r.notify(dropped...)
+	if updated {
+		r.notify(ChangeEvent[T]{Op: OpUpdate, Key: key, Old: prev, New: obj})
+	}
+	return updated
+}
+
+// Replace element by primary key only if eq reports it's equal to expected.
+// Check and replacement are atomic against concurrent writers of the same key,
+// secondary indexes are updated like in Update. Returns true if element was replaced.
+// Eq runs under primary key lock and must not call methods of the map.
+func (r *IndexedMap[T]) CompareAndSwap(k string, expected, new T, eq func(a, b T) bool) bool {
+	return r.Update(k, func(obj *T) bool {
+		if !eq(*obj, expected) {
+			return false
+		}
+		*obj = new
+		return true
+	})
+}
+
+// Put all elements of other map to this one, indexing them with own index functions.
+// Element of other map replaces existing element with the same key like in Put.
+// Expired elements of other map and elements violating unique indexes are skipped.
+func (r *IndexedMap[T]) Merge(other *IndexedMap[T]) {
+	r.MergeFunc(other, nil)
+}
+
+// Put all elements of other map to this one, resolving key collisions with resolve.
+// Resolve gets existing and incoming elements and returns the one to store,
+// it runs under primary key lock and must not call methods of the map.
+// Nil resolve keeps incoming element, see Merge.
+func (r *IndexedMap[T]) MergeFunc(other *IndexedMap[T], resolve func(existing, incoming T) T) {
+	r.checkOpen()
+	other.primary.Range(func(k string, v any) bool {
+		if other.expired(k) {
+			return true
 		}
+		orig := other.originalKey(k)
+		key := r.normalize(orig)
+		var prev, obj T
+		var loaded bool
+		var dropped []ChangeEvent[T]
+		var err error
+		r.withRLock(func() {
+			prev, obj, loaded, dropped, err = r.merge(key, orig, r.unbox(v), resolve)
+		})
+		r.notify(dropped...)
+		if err == nil {
+			r.notifyPut(key, prev, obj, loaded)
+			r.evict()
+		}
+		return true
+	})
+}
+
+// Put element resolving collision with existing one, expired element is replaced without resolve.
+// Returns previous and stored elements and true if key was present.
+func (r *IndexedMap[T]) merge(key string, orig string, obj T, resolve func(existing, incoming T) T) (T, T, bool, []ChangeEvent[T], error) {
+	if resolve == nil {
+		prev, loaded, dropped, err := r.put(key, orig, obj)
+		return prev, obj, loaded, dropped, err
 	}
-	r.primary.Store(key, obj)
+	var prev T
+	var found bool
+	var err error
+	dropped := r.compute(key, func(old any, loaded bool) (any, bool) {
+		if loaded {
+			prev, found = r.unbox(old), true
+			obj = resolve(prev, obj)
+		}
+		if err = r.lockUnique(key, &obj); err != nil {
+			return old, !loaded
+		}
+		defer r.unlockUnique()
+		r.setExpiry(key, 0)
+		if loaded {
+			for index := range r.indexes {
+				r.updateIndex(index, &obj, &prev, key)
+			}
+			r.setOrigKey(key, orig)
+			r.touch(key)
+			r.puts.Add(1)
+		} else {
+			r.insert(key, orig, &obj)
+		}
+		return r.box(&obj), false
+	})
+	return prev, obj, found, dropped, err
 }
 
 // Put all array elements to indexed map. For arrays with more than 10k elements it works in parallel.
 // keyFunc provides key extractor.
+// Elements are copied like in Put, so changing arr afterwards doesn't affect the map.
 func (r *IndexedMap[T]) PutAll(arr []T, keyFunc func(*T) string) {
+	r.PutAllWith(arr, keyFunc, runtime.NumCPU())
+}
+
+// Put all array elements like PutAll, using at most parallelism goroutines for arrays with more than 10k elements.
+// Parallelism of 1 puts elements serially in the calling goroutine, not positive one panics with ErrInvalidParallelism.
+// Closed map is checked once before putting, so workers never panic with ErrClosed.
+func (r *IndexedMap[T]) PutAllWith(arr []T, keyFunc func(*T) string, parallelism int) {
+	r.checkOpen()
+	parallelism = threads(len(arr), parallelism)
+	put := func(obj T) {
+		k := keyFunc(&obj)
+		r.putKey(r.normalize(k), k, obj)
+	}
 	count := len(arr)
-	if count < 10000 {
+	if !parallel(count, parallelism) {
 		for _, t := range arr {
-			r.Put(keyFunc(&t), t)
+			put(t)
 		}
 		return
 	}
-	threads := runtime.NumCPU()
-	batch := count / (threads - 1)
-	ch := make(chan int, runtime.NumCPU())
-	for i := range threads {
-		go func() {
-			for j := i * batch; j < (i+1)*batch; j++ {
-				if j >= count {
-					break
-				}
-				r.Put(keyFunc(&arr[j]), arr[j])
-			}
-			ch <- 1
-		}()
+	r.pool().partition(count, parallelism, func(part, lo, hi int) {
+		for j := lo; j < hi; j++ {
+			put(arr[j])
+		}
+	})
+}
+
+// Put all map entries to indexed map. For maps with more than 10k entries it works in parallel like PutAll.
+// Keys are normalized like in Put.
+func (r *IndexedMap[T]) PutAllMap(m map[string]T) {
+	r.PutAllMapWith(m, runtime.NumCPU())
+}
+
+// Put all map entries like PutAllMap, using at most parallelism goroutines like PutAllWith.
+func (r *IndexedMap[T]) PutAllMapWith(m map[string]T, parallelism int) {
+	r.checkOpen()
+	parallelism = threads(len(m), parallelism)
+	count := len(m)
+	if !parallel(count, parallelism) {
+		for k, v := range m {
+			r.putKey(r.normalize(k), k, v)
+		}
+		return
+	}
+	keys := make([]string, 0, count)
+	for k := range m {
+		keys = append(keys, k)
 	}
-	waitChan(ch, threads)
-	close(ch)
+	r.pool().partition(count, parallelism, func(part, lo, hi int) {
+		for _, k := range keys[lo:hi] {
+			r.putKey(r.normalize(k), k, m[k])
+		}
+	})
+}
+
+// Check whether count elements are worth splitting between threads goroutines.
+func parallel(count int, threads int) bool {
+	return count >= 10000 && threads > 1
 }
 
 func waitChan(c chan int, num int) {
@@ -110,16 +693,61 @@ func (r *IndexedMap[T]) ContainsKey(key string) bool {
 	return ok
 }
 
+// Check if all keys are present, stops on first missing one.
+// Returns true for empty keys.
+func (r *IndexedMap[T]) ContainsAllKeys(keys ...string) bool {
+	t := r.mu.RLock()
+	defer r.mu.RUnlock(t)
+	for _, k := range keys {
+		if _, ok := r.get(r.normalize(k)); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// Check if any of keys is present, stops on first present one.
+// Returns false for empty keys.
+func (r *IndexedMap[T]) ContainsAnyKeys(keys ...string) bool {
+	t := r.mu.RLock()
+	defer r.mu.RUnlock(t)
+	for _, k := range keys {
+		if _, ok := r.get(r.normalize(k)); ok {
+			return true
+		}
+	}
+	return false
+}
+
 // Get element from primary index by int key.
 func (r *IndexedMap[T]) GetInt(key int) (T, bool) {
-	return r.Get(strconv.Itoa(key))
+	t := r.mu.RLock()
+	defer r.mu.RUnlock(t)
+	return r.get(r.intKey(key))
 }
 
 // Get element from primary index.
 func (r *IndexedMap[T]) Get(key string) (T, bool) {
-	o, ok := r.primary.Load(strings.ToUpper(key))
-	if ok {
-		return o.(T), true
+	t := r.mu.RLock()
+	defer r.mu.RUnlock(t)
+	return r.get(r.normalize(key))
+}
+
+// Get element from primary index.
+// Unlike Get, returns ErrKeyNotFound for missing key.
+func (r *IndexedMap[T]) GetErr(key string) (T, error) {
+	obj, ok := r.Get(key)
+	if !ok {
+		return obj, fmt.Errorf("%w: %s", ErrKeyNotFound, key)
+	}
+	return obj, nil
+}
+
+func (r *IndexedMap[T]) get(key string) (T, bool) {
+	o, ok := r.primary.Load(key)
+	if ok && !r.expired(key) {
+		r.touch(key)
+		return r.unbox(o), true
 	}
 	var zero T
 	return zero, false
@@ -129,113 +757,1390 @@ func (r *IndexedMap[T]) Get(key string) (T, bool) {
 // This method has eventual consistency when secondary indexes are updated.
 // There is a possibility that element will exist in primary index while partially removed from secondary indexes.
 func (r *IndexedMap[T]) RemoveInt(key int) (T, bool) {
-	return r.Remove(strconv.Itoa(key))
+	r.checkOpen()
+	return r.removeKey(r.intKey(key))
 }
 
 // Remove element from map by primary key.
 // This method has eventual consistency when secondary indexes are updated.
 // There is a possibility that element will exist in primary index while partially removed from secondary indexes.
 func (r *IndexedMap[T]) Remove(k string) (T, bool) {
-	key := strings.ToUpper(k)
-	o, ok := r.Get(key)
-	if ok {
-		for name := range r.secondary {
-			r.removeFromAllIndexLists(name, key)
-		}
-		r.primary.Delete(key)
-		return o, true
+	r.checkOpen()
+	return r.removeKey(r.normalize(k))
+}
+
+// Remove element from map by primary key and get removed element.
+// Unlike Remove, returns ErrKeyNotFound for missing key and ErrClosed instead of panic for closed map.
+func (r *IndexedMap[T]) RemoveErr(k string) (T, error) {
+	if r.closed.Load() {
+		var zero T
+		return zero, ErrClosed
 	}
-	var zero T
-	return zero, false
+	obj, ok := r.Remove(k)
+	if !ok {
+		return obj, fmt.Errorf("%w: %s", ErrKeyNotFound, k)
+	}
+	return obj, nil
 }
 
-func (r *IndexedMap[T]) removeFromAllIndexLists(name string, key string) {
-	r.secondary[name].Range(func(k string, v any) bool {
-		m := v.(*xsync.Map)
-		m.Delete(key)
-		return true
-	})
+// Fetch and remove element by primary key, e.g. to take work items from the map.
+// Removal goes under primary key lock like in Remove, so only one of concurrent callers gets the element.
+func (r *IndexedMap[T]) GetAndRemove(k string) (T, bool) {
+	r.checkOpen()
+	return r.removeKey(r.normalize(k))
 }
 
-// Get all keys from primary index.
-func (r *IndexedMap[T]) Keys() []string {
-	keys := make([]string, 0, r.Size())
-	r.primary.Range(func(k string, b any) bool {
-		keys = append(keys, k)
-		return true
+// Move element from oldKey to newKey, reindexing it under the new key and keeping its expiration.
+// Returns false and changes nothing if oldKey is missing or newKey is already present.
+// Runs under exclusive lock like Transaction, so lookups see the element either under old or new key,
+// while iteration which doesn't block writers may see it under both or neither.
+// Change listeners get OpDelete of old key and OpInsert of new key.
+func (r *IndexedMap[T]) Rename(oldKey, newKey string) bool {
+	from, to := r.normalize(oldKey), r.normalize(newKey)
+	r.checkOpen()
+	var obj T
+	var dropped []ChangeEvent[T]
+	moved := false
+	r.withLock(func() {
+		var ok bool
+		if obj, ok = r.get(from); !ok || from == to {
+			return
+		}
+		if _, ok := r.get(to); ok {
+			return
+		}
+		orig, deadline := r.originalKey(from), r.deadline(from)
+		r.remove(from)
+		var err error
+		// expired element of newKey is replaced, its removal is reported before the move
+		if _, _, dropped, err = r.putExpiring(to, newKey, obj, deadline); err != nil {
+			// can't happen as the element released its unique values, restore it anyway
+			r.putExpiring(from, orig, obj, deadline)
+			return
+		}
+		moved = true
 	})
-	return keys
+	r.notify(dropped...)
+	if !moved {
+		return false
+	}
+	r.notify(ChangeEvent[T]{Op: OpDelete, Key: from, Old: obj}, ChangeEvent[T]{Op: OpInsert, Key: to, New: obj})
+	return true
 }
 
-func (r *IndexedMap[T]) updateIndex(name string, obj *T, key string) {
-	indexValue := strings.ToUpper(r.indexes[name](obj))
-	prev, ok := r.Get(key)
-	prevValue := ""
+// Remove element by already normalized key.
+// Callers check that the map is open before.
+func (r *IndexedMap[T]) removeKey(key string) (T, bool) {
+	var o T
+	var ok bool
+	var dropped []ChangeEvent[T]
+	r.withRLock(func() {
+		o, ok, dropped = r.remove(key)
+	})
+	r.notify(dropped...)
 	if ok {
-		prevValue = strings.ToUpper(r.indexes[name](&prev))
-	}
-	if indexValue == "" && prevValue == "" {
-		return
-	} else if indexValue != "" && indexValue == prevValue {
-		r.putToIndex(name, indexValue, obj, key)
-	} else if indexValue != "" && prevValue != "" && indexValue != prevValue {
-		r.putToIndex(name, indexValue, obj, key)
-		r.getIndexMapList(name, prevValue).Delete(key)
-	} else if prevValue != "" && indexValue == "" {
-		r.getIndexMapList(name, prevValue).Delete(key)
+		r.notify(ChangeEvent[T]{Op: OpDelete, Key: key, Old: o})
 	}
+	return o, ok
 }
 
-func (r *IndexedMap[T]) getIndexMapList(name, indexValue string) *xsync.Map {
-	v, ok := r.secondary[name].Load(indexValue)
-	if !ok {
-		v, _ = r.secondary[name].LoadOrStore(indexValue, xsync.NewMap())
+func (r *IndexedMap[T]) remove(key string) (T, bool, []ChangeEvent[T]) {
+	return r.removeIf(key, nil)
+}
+
+// Remove element if pred is nil or returns true for it.
+// Predicate is checked under primary key lock, so element can't change in between.
+// Expired element is removed as absent one, its removal is returned as event, see compute.
+func (r *IndexedMap[T]) removeIf(key string, pred func(T) bool) (T, bool, []ChangeEvent[T]) {
+	var removed T
+	var ok bool
+	dropped := r.compute(key, func(old any, loaded bool) (any, bool) {
+		if !loaded || (pred != nil && !pred(r.unbox(old))) {
+			return old, !loaded
+		}
+		removed, ok = r.unbox(old), true
+		r.unindex(key)
+		return old, true
+	})
+	return removed, ok, dropped
+}
+
+// Remove element from secondary indexes and bookkeeping before it's deleted from primary index.
+// Must be called under primary key lock.
+func (r *IndexedMap[T]) unindex(key string) {
+	for name := range r.secondary {
+		r.removeFromAllIndexLists(name, key)
 	}
-	return v.(*xsync.Map)
+	r.setExpiry(key, 0)
+	r.origKeys.Delete(key)
+	if r.lru != nil {
+		r.lru.remove(key)
+	}
+	r.size.Add(-1)
+	r.removes.Add(1)
 }
 
-func (r *IndexedMap[T]) putToIndex(name string, indexValue string, obj *T, key string) {
-	r.getIndexMapList(name, indexValue).Store(key, obj)
+// Remove all elements having index value from map.
+// Returns number of removed elements.
+// Elements added with this index value while removal is in progress may be kept.
+func (r *IndexedMap[T]) RemoveByIndex(name string, v string) int {
+	r.checkOpen()
+	var removed, events []ChangeEvent[T]
+	r.withRLock(func() {
+		m, ok := r.findIndexMapList(name, r.normalize(v))
+		if !ok {
+			return
+		}
+		keys := []string{}
+		m.Range(func(k string, v any) bool {
+			keys = append(keys, k)
+			return true
+		})
+		removed = make([]ChangeEvent[T], 0, len(keys))
+		for _, key := range keys {
+			o, ok, dropped := r.remove(key)
+			events = append(events, dropped...)
+			if ok {
+				removed = append(removed, ChangeEvent[T]{Op: OpDelete, Key: key, Old: o})
+			}
+		}
+	})
+	r.notify(append(events, removed...)...)
+	return len(removed)
 }
 
-// Find all elements by index value.
-func (r *IndexedMap[T]) GetByIndex(name string, v string) []T {
-	indexValue := strings.ToUpper(v)
-	result := []T{}
-	r.getIndexMapList(name, indexValue).Range(func(k string, v any) bool {
-		result = append(result, *v.(*T))
-		return true
+// Remove all elements for which pred returns true from primary and secondary indexes.
+// Returns number of removed elements.
+// Pred is rechecked under primary key lock before removal, so element changed in between is kept
+// unless it still matches. Pred must not call methods of the map.
+func (r *IndexedMap[T]) RemoveIf(pred func(T) bool) int {
+	r.checkOpen()
+	removed := []ChangeEvent[T]{}
+	var events []ChangeEvent[T]
+	r.withRLock(func() {
+		r.primary.Range(func(k string, v any) bool {
+			if !pred(r.unbox(v)) {
+				return true
+			}
+			o, ok, dropped := r.removeIf(k, pred)
+			events = append(events, dropped...)
+			if ok {
+				removed = append(removed, ChangeEvent[T]{Op: OpDelete, Key: k, Old: o})
+			}
+			return true
+		})
 	})
-	return result
+	r.notify(append(events, removed...)...)
+	return len(removed)
 }
 
-// Get all values for specified index.
-func (r *IndexedMap[T]) GetIndexKeys(name string) []string {
-	result := []string{}
-	r.secondary[name].Range(func(k string, v any) bool {
-		result = append(result, k)
-		return true
+// Remove elements by primary keys from primary and secondary indexes.
+// Returns number of elements which were present and removed.
+// For more than 10k keys removal works in parallel like PutAll.
+// Index values of removed elements left without elements are dropped afterwards, briefly blocking other operations.
+func (r *IndexedMap[T]) RemoveAll(keys []string) int {
+	return r.RemoveAllWith(keys, runtime.NumCPU())
+}
+
+// Remove elements by primary keys like RemoveAll, using at most parallelism goroutines like PutAllWith.
+func (r *IndexedMap[T]) RemoveAllWith(keys []string, parallelism int) int {
+	r.checkOpen()
+	parallelism = threads(len(keys), parallelism)
+	count := len(keys)
+	var events []ChangeEvent[T]
+	var values map[indexValue]struct{}
+	n := 0
+	r.withRLock(func() {
+		if !parallel(count, parallelism) {
+			events, n = r.removeKeys(keys)
+		} else {
+			parts := make([][]ChangeEvent[T], parallelism)
+			counts := make([]int, parallelism)
+			r.pool().partition(count, parallelism, func(part, lo, hi int) {
+				parts[part], counts[part] = r.removeKeys(keys[lo:hi])
+			})
+			events = slices.Concat(parts...)
+			for _, c := range counts {
+				n += c
+			}
+		}
+		values = r.removedIndexValues(events)
 	})
-	return result
+	if len(values) > 0 {
+		r.withLock(func() {
+			r.dropEmptyIndexValues(values)
+		})
+	}
+	r.notify(events...)
+	return n
 }
 
-// Get underlying sync.Map for selected index and value.
-func (r *IndexedMap[T]) GetByIndexUnderlyingMap(name string, v string) *xsync.Map {
-	indexValue := strings.ToUpper(v)
-	return r.getIndexMapList(name, indexValue)
+// Remove elements by keys, returns removal events, including ones of expired elements,
+// and number of removed elements which were not expired.
+func (r *IndexedMap[T]) removeKeys(keys []string) ([]ChangeEvent[T], int) {
+	events := []ChangeEvent[T]{}
+	n := 0
+	for _, k := range keys {
+		key := r.normalize(k)
+		o, ok, dropped := r.remove(key)
+		events = append(events, dropped...)
+		if ok {
+			events = append(events, ChangeEvent[T]{Op: OpDelete, Key: key, Old: o})
+			n++
+		}
+	}
+	return events, n
 }
 
-// Get underlying sync.Map for primary index.
-func (r *IndexedMap[T]) GetPrimaryIndexUnderlyingMap() *xsync.Map {
-	return r.primary
+// Index value of secondary index
+type indexValue struct {
+	name  string
+	value string
 }
 
-// Count elements in the indexed map.
-func (r *IndexedMap[T]) Size() int {
-	var i int
-	r.primary.Range(func(k string, v any) bool {
-		i++
-		return true
-	})
-	return i
+// Collect index values of removed elements, must be called under reader side of map lock.
+func (r *IndexedMap[T]) removedIndexValues(events []ChangeEvent[T]) map[indexValue]struct{} {
+	values := map[indexValue]struct{}{}
+	for _, e := range events {
+		for name := range r.indexes {
+			for _, v := range r.indexValues(name, &e.Old) {
+				values[indexValue{name, v}] = struct{}{}
+			}
+		}
+	}
+	return values
+}
+
+// Drop given index value collections if they are left without elements, must be called under exclusive lock,
+// as writers could add elements to collections being dropped otherwise.
+// Only these values are checked, so the lock is held for time proportional to their number, not to index size.
+func (r *IndexedMap[T]) dropEmptyIndexValues(values map[indexValue]struct{}) {
+	for iv := range values {
+		index, ok := r.secondary[iv.name]
+		if !ok {
+			// index removed in between
+			continue
+		}
+		if m, ok := index.Load(iv.value); ok && m.(*xsync.Map).Size() == 0 {
+			index.Delete(iv.value)
+			if s, ok := r.ordered[iv.name]; ok {
+				s.remove(iv.value)
+			}
+		}
+	}
+}
+
+func (r *IndexedMap[T]) removeFromAllIndexLists(name string, key string) {
+	r.secondary[name].Range(func(k string, v any) bool {
+		m := v.(*xsync.Map)
+		m.Delete(key)
+		return true
+	})
+}
+
+// Get all keys from primary index.
+// Keys are returned as passed to the last put of each element, while lookups by them ignore case.
+func (r *IndexedMap[T]) Keys() []string {
+	t := r.mu.RLock()
+	defer r.mu.RUnlock(t)
+	keys := make([]string, 0, r.Size())
+	r.primary.Range(func(k string, b any) bool {
+		if !r.expired(k) {
+			keys = append(keys, r.originalKey(k))
+		}
+		return true
+	})
+	return keys
+}
+
+// Call fn for each element of the map until it returns false.
+// Elements are passed by value, so changing them doesn't affect the map.
+// Keys are passed in original casing like in Keys.
+// Like the underlying map Range, it doesn't correspond to a consistent snapshot:
+// concurrent modifications may or may not be observed. Expired elements are skipped.
+func (r *IndexedMap[T]) ForEach(fn func(key string, value T) bool) {
+	r.primary.Range(func(k string, v any) bool {
+		if r.expired(k) {
+			return true
+		}
+		return fn(r.originalKey(k), r.unbox(v))
+	})
+}
+
+// Get all keys from primary index collected in a single pass, same as Keys.
+// Keys removed or added during the pass may be missed, but no key is returned twice,
+// so iterating the result with Get gives stable order and visits every key once.
+func (r *IndexedMap[T]) KeysSnapshot() []string {
+	return r.Keys()
+}
+
+// Call fn for each element of keys snapshot taken before the first call, see KeysSnapshot.
+// Unlike ForEach, every key is visited at most once, elements are read when visited
+// and keys removed after the snapshot is taken are skipped. Keys added after it are not visited.
+func (r *IndexedMap[T]) ForEachSnapshot(fn func(key string, value T)) {
+	t := r.mu.RLock()
+	keys := make([]string, 0, r.Size())
+	r.primary.Range(func(k string, _ any) bool {
+		keys = append(keys, k)
+		return true
+	})
+	r.mu.RUnlock(t)
+	for _, k := range keys {
+		if obj, ok := r.lookup(k); ok {
+			fn(r.originalKey(k), obj)
+		}
+	}
+}
+
+// Get all elements from primary index.
+func (r *IndexedMap[T]) Values() []T {
+	values := make([]T, 0, r.Size())
+	r.ForEach(func(key string, value T) bool {
+		values = append(values, value)
+		return true
+	})
+	return values
+}
+
+// Primary key and element pair returned by Entries.
+type Entry[T any] struct {
+	Key   string
+	Value T
+}
+
+// Get all key and element pairs collected in a single pass over primary index,
+// so each pair is consistent, unlike zipping Keys and Values.
+// Keys are in original casing like in Keys, elements are copies.
+func (r *IndexedMap[T]) Entries() []Entry[T] {
+	entries := make([]Entry[T], 0, r.Size())
+	r.ForEach(func(key string, value T) bool {
+		entries = append(entries, Entry[T]{Key: key, Value: value})
+		return true
+	})
+	return entries
+}
+
+// Copy all elements to dst by normalized primary key, overwriting elements already stored there.
+// Useful to aggregate several maps without allocating a new one.
+// Only this map is read concurrently safely, dst must not be used by other goroutines during the call.
+func (r *IndexedMap[T]) CopyInto(dst map[string]T) {
+	r.primary.Range(func(k string, v any) bool {
+		if !r.expired(k) {
+			dst[k] = r.unbox(v)
+		}
+		return true
+	})
+}
+
+// Find all elements for which pred returns true in a single pass over primary index.
+// Returns empty slice if nothing matches. Like ForEach, it doesn't correspond to a consistent snapshot.
+func (r *IndexedMap[T]) Filter(pred func(T) bool) []T {
+	result := []T{}
+	r.ForEach(func(key string, value T) bool {
+		if pred(value) {
+			result = append(result, value)
+		}
+		return true
+	})
+	return result
+}
+
+// Group all elements by key computed with fn, like one-shot index which isn't kept in the map.
+// Group keys are used as is, without normalization, and empty key forms its own group.
+func (r *IndexedMap[T]) GroupBy(fn func(T) string) map[string][]T {
+	result := map[string][]T{}
+	r.ForEach(func(key string, value T) bool {
+		g := fn(value)
+		result[g] = append(result[g], value)
+		return true
+	})
+	return result
+}
+
+// Compute normalized index value of element stored by primary key, e.g. to check index function.
+// For multi-value index the smallest value is returned, see IndexValuesOf.
+// Returns empty string if element isn't indexed, and false if key or index is missing.
+func (r *IndexedMap[T]) IndexValueOf(name, key string) (string, bool) {
+	values, ok := r.IndexValuesOf(name, key)
+	if !ok || len(values) == 0 {
+		return "", ok
+	}
+	return values[0], true
+}
+
+// Compute all normalized index values of element stored by primary key in ascending order.
+// Returns false if key or index is missing.
+func (r *IndexedMap[T]) IndexValuesOf(name, key string) ([]string, bool) {
+	t := r.mu.RLock()
+	defer r.mu.RUnlock(t)
+	if _, ok := r.indexes[name]; !ok {
+		return nil, false
+	}
+	obj, ok := r.lookup(r.normalize(key))
+	if !ok {
+		return nil, false
+	}
+	return r.indexValues(name, &obj), true
+}
+
+// Get normalized values of the element for index, sorted and without duplicates,
+// so index functions may return raw values. Empty values are not indexed.
+func (r *IndexedMap[T]) indexValues(name string, obj *T) []string {
+	values := r.indexes[name](obj)
+	result := make([]string, 0, len(values))
+	for _, v := range values {
+		if v != "" {
+			result = append(result, r.normalize(v))
+		}
+	}
+	if len(result) > 1 {
+		slices.Sort(result)
+		result = slices.Compact(result)
+	}
+	return result
+}
+
+// Move element between index values, must be called under primary key lock.
+// New values are added before stale ones are removed,
+// so changing element is never absent from both its old and new value collections.
+func (r *IndexedMap[T]) updateIndex(name string, obj *T, prev *T, key string) {
+	values := r.indexValues(name, obj)
+	prevValues := r.indexValues(name, prev)
+	for _, v := range sortedDiff(values, prevValues) {
+		r.putToIndex(name, v, key)
+	}
+	for _, v := range sortedDiff(prevValues, values) {
+		if m, ok := r.findIndexMapList(name, v); ok {
+			m.Delete(key)
+		}
+	}
+}
+
+// Get values of sorted set a missing in sorted set b in a single pass over both.
+func sortedDiff(a, b []string) []string {
+	var result []string
+	j := 0
+	for _, v := range a {
+		for j < len(b) && b[j] < v {
+			j++
+		}
+		if j == len(b) || b[j] != v {
+			result = append(result, v)
+		}
+	}
+	return result
+}
+
+func (r *IndexedMap[T]) getIndexMapList(name, indexValue string) *xsync.Map {
+	v, ok := r.secondary[name].Load(indexValue)
+	if !ok {
+		var loaded bool
+		v, loaded = r.secondary[name].LoadOrStore(indexValue, xsync.NewMap())
+		if s, ok := r.ordered[name]; ok && !loaded {
+			s.add(indexValue)
+		}
+	}
+	return v.(*xsync.Map)
+}
+
+// Load-only counterpart of getIndexMapList for read paths, doesn't create missing collections.
+// Unknown index is treated as index without values.
+func (r *IndexedMap[T]) findIndexMapList(name, indexValue string) (*xsync.Map, bool) {
+	index, ok := r.secondary[name]
+	if !ok {
+		return nil, false
+	}
+	v, ok := index.Load(indexValue)
+	if !ok {
+		return nil, false
+	}
+	return v.(*xsync.Map), true
+}
+
+// Add primary key to index value collection.
+// Collections keep only keys, elements are resolved through primary index, so both always agree.
+func (r *IndexedMap[T]) putToIndex(name string, indexValue string, key string) {
+	r.getIndexMapList(name, indexValue).Store(key, struct{}{})
+}
+
+// Get element from primary index for key found in secondary index.
+// Element may be missing if it's removed concurrently.
+func (r *IndexedMap[T]) lookup(key string) (T, bool) {
+	v, ok := r.primary.Load(key)
+	if !ok || r.expired(key) {
+		var zero T
+		return zero, false
+	}
+	return r.unbox(v), true
+}
+
+// Find all elements by index value.
+func (r *IndexedMap[T]) GetByIndex(name string, v string) []T {
+	return r.GetByIndexInto(name, v, []T{})
+}
+
+// Index name and value pair for GetByIndexBatch.
+type IndexQuery struct {
+	Name  string
+	Value string
+}
+
+// Run independent GetByIndex queries in parallel.
+// Returns results in the same order as queries.
+func (r *IndexedMap[T]) GetByIndexBatch(queries []IndexQuery) [][]T {
+	return r.GetByIndexBatchWith(queries, runtime.NumCPU())
+}
+
+// Run independent GetByIndex queries like GetByIndexBatch, using at most parallelism goroutines.
+// Parallelism of 1 runs queries serially in the calling goroutine, not positive one panics with ErrInvalidParallelism.
+func (r *IndexedMap[T]) GetByIndexBatchWith(queries []IndexQuery, parallelism int) [][]T {
+	parallelism = threads(len(queries), parallelism)
+	result := make([][]T, len(queries))
+	if parallelism == 1 {
+		for j, q := range queries {
+			result[j] = r.GetByIndex(q.Name, q.Value)
+		}
+		return result
+	}
+	r.pool().partition(len(queries), parallelism, func(part, lo, hi int) {
+		for j := lo; j < hi; j++ {
+			result[j] = r.GetByIndex(queries[j].Name, queries[j].Value)
+		}
+	})
+	return result
+}
+
+// Find all elements by index value, appending them to dst truncated to zero length.
+// Returns the grown slice, so callers can reuse buffers between calls.
+func (r *IndexedMap[T]) GetByIndexInto(name string, v string, dst []T) []T {
+	t := r.mu.RLock()
+	defer r.mu.RUnlock(t)
+	indexValue := r.normalize(v)
+	result := dst[:0]
+	m, ok := r.findIndexMapList(name, indexValue)
+	if !ok {
+		return result
+	}
+	m.Range(func(k string, _ any) bool {
+		if obj, ok := r.lookup(k); ok {
+			result = append(result, obj)
+		}
+		return true
+	})
+	return result
+}
+
+// Find all elements by index value, reading them from primary index in parallel.
+// For values with more than 10k elements keys are split between goroutines like in PutAll,
+// smaller values are read serially like in GetByIndex. Order of elements is arbitrary in both cases.
+func (r *IndexedMap[T]) GetByIndexParallel(name string, v string) []T {
+	return r.GetByIndexParallelWith(name, v, runtime.NumCPU())
+}
+
+// Find all elements by index value like GetByIndexParallel, using at most parallelism goroutines like PutAllWith.
+func (r *IndexedMap[T]) GetByIndexParallelWith(name string, v string, parallelism int) []T {
+	checkParallelism(parallelism)
+	t := r.mu.RLock()
+	defer r.mu.RUnlock(t)
+	m, ok := r.findIndexMapList(name, r.normalize(v))
+	if !ok {
+		return []T{}
+	}
+	keys := make([]string, 0, m.Size())
+	m.Range(func(k string, _ any) bool {
+		keys = append(keys, k)
+		return true
+	})
+	if parallelism = threads(len(keys), parallelism); !parallel(len(keys), parallelism) {
+		return r.lookupAll(keys)
+	}
+	parts := make([][]T, parallelism)
+	r.pool().partition(len(keys), parallelism, func(part, lo, hi int) {
+		parts[part] = r.lookupAll(keys[lo:hi])
+	})
+	return slices.Concat(parts...)
+}
+
+// Get elements from primary index for keys, skipping missing ones.
+func (r *IndexedMap[T]) lookupAll(keys []string) []T {
+	result := make([]T, 0, len(keys))
+	for _, k := range keys {
+		if obj, ok := r.lookup(k); ok {
+			result = append(result, obj)
+		}
+	}
+	return result
+}
+
+// Find all elements by index value, verifying each of them against primary index.
+// Unlike GetByIndex, it never returns stale element which has already been moved to another index value,
+// and returned elements are read from primary index. Element which isn't changed during the call
+// is always returned. Element changed concurrently reflects either its state before
+// or after the change, but the result as a whole isn't a snapshot of single moment.
+func (r *IndexedMap[T]) GetByIndexConsistent(name string, v string) []T {
+	t := r.mu.RLock()
+	defer r.mu.RUnlock(t)
+	indexValue := r.normalize(v)
+	result := []T{}
+	m, ok := r.findIndexMapList(name, indexValue)
+	if !ok {
+		return result
+	}
+	m.Range(func(k string, _ any) bool {
+		obj, ok := r.get(k)
+		if ok && slices.Contains(r.indexValues(name, &obj), indexValue) {
+			result = append(result, obj)
+		}
+		return true
+	})
+	return result
+}
+
+// Find all elements by index value sorted with less.
+// Sort is stable, but elements of the same index value don't have defined order,
+// so elements equal by less may come in any order.
+func (r *IndexedMap[T]) GetByIndexSorted(name string, v string, less func(a, b T) bool) []T {
+	result := r.GetByIndex(name, v)
+	sort.SliceStable(result, func(i, j int) bool {
+		return less(result[i], result[j])
+	})
+	return result
+}
+
+// Find at most limit primary keys of elements by index value together with number of all its elements,
+// counted in the same pass over the index value, e.g. to render the first page and the total.
+// Which keys are returned is arbitrary, keys are in original casing like in Keys.
+// Non-positive limit returns only the count.
+func (r *IndexedMap[T]) GetKeysByIndexWithCount(name string, v string, limit int) ([]string, int) {
+	t := r.mu.RLock()
+	defer r.mu.RUnlock(t)
+	keys := []string{}
+	total := 0
+	m, ok := r.findIndexMapList(name, r.normalize(v))
+	if !ok {
+		return keys, total
+	}
+	m.Range(func(k string, _ any) bool {
+		if r.expired(k) {
+			return true
+		}
+		if total < limit {
+			keys = append(keys, r.originalKey(k))
+		}
+		total++
+		return true
+	})
+	return keys, total
+}
+
+// Find primary keys of elements by index value without copying elements.
+// Keys are returned in original casing like in Keys.
+func (r *IndexedMap[T]) GetKeysByIndex(name string, v string) []string {
+	t := r.mu.RLock()
+	defer r.mu.RUnlock(t)
+	result := []string{}
+	m, ok := r.findIndexMapList(name, r.normalize(v))
+	if !ok {
+		return result
+	}
+	m.Range(func(k string, _ any) bool {
+		if !r.expired(k) {
+			result = append(result, r.originalKey(k))
+		}
+		return true
+	})
+	return result
+}
+
+// Find elements by index value together with their primary keys.
+// Elements are read from primary index, keys removed concurrently are skipped.
+// Keys are in original casing like in Keys.
+func (r *IndexedMap[T]) GetByIndexWithKeys(name string, v string) map[string]T {
+	t := r.mu.RLock()
+	defer r.mu.RUnlock(t)
+	result := map[string]T{}
+	m, ok := r.findIndexMapList(name, r.normalize(v))
+	if !ok {
+		return result
+	}
+	m.Range(func(k string, _ any) bool {
+		if obj, ok := r.lookup(k); ok {
+			result[r.originalKey(k)] = obj
+		}
+		return true
+	})
+	return result
+}
+
+// Call fn for each element having index value until it returns false.
+// Elements are passed by value one by one, so large results are never materialized.
+// Index lock is not held while fn runs, so it may call methods of the map.
+func (r *IndexedMap[T]) RangeByIndex(name string, v string, fn func(T) bool) {
+	t := r.mu.RLock()
+	m, ok := r.findIndexMapList(name, r.normalize(v))
+	r.mu.RUnlock(t)
+	if !ok {
+		return
+	}
+	m.Range(func(k string, _ any) bool {
+		if obj, ok := r.lookup(k); ok {
+			return fn(obj)
+		}
+		return true
+	})
+}
+
+// Call fn for each value of index with elements having it, until fn returns false.
+// Elements of one value are materialized at a time, values without elements are skipped.
+// Like RangeByIndex, index lock is not held while fn runs, see IndexGroups for streaming variant.
+func (r *IndexedMap[T]) WalkIndex(name string, fn func(value string, members []T) bool) {
+	r.walkIndex(name, func(value string, m *xsync.Map) bool {
+		members := []T{}
+		m.Range(func(k string, _ any) bool {
+			if obj, ok := r.lookup(k); ok {
+				members = append(members, obj)
+			}
+			return true
+		})
+		if len(members) == 0 {
+			return true
+		}
+		return fn(value, members)
+	})
+}
+
+// Range value collections of index without holding index lock.
+func (r *IndexedMap[T]) walkIndex(name string, fn func(value string, m *xsync.Map) bool) {
+	t := r.mu.RLock()
+	index, ok := r.secondary[name]
+	r.mu.RUnlock(t)
+	if !ok {
+		return
+	}
+	index.Range(func(value string, v any) bool {
+		return fn(value, v.(*xsync.Map))
+	})
+}
+
+// Find all elements by index value, or get def if nothing matches or index is unknown.
+func (r *IndexedMap[T]) GetByIndexOrDefault(name string, v string, def []T) []T {
+	if result := r.GetByIndex(name, v); len(result) > 0 {
+		return result
+	}
+	return def
+}
+
+// Find all elements by index value.
+// Unlike GetByIndex, returns ErrIndexNotFound for unknown index.
+func (r *IndexedMap[T]) GetByIndexErr(name string, v string) ([]T, error) {
+	t := r.mu.RLock()
+	_, ok := r.indexes[name]
+	r.mu.RUnlock(t)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrIndexNotFound, name)
+	}
+	return r.GetByIndex(name, v), nil
+}
+
+// Find single element by index value.
+// Intended for unique indexes, for non-unique ones it's unspecified which of matching elements is returned.
+func (r *IndexedMap[T]) GetOneByIndex(name string, v string) (T, bool) {
+	t := r.mu.RLock()
+	defer r.mu.RUnlock(t)
+	var result T
+	found := false
+	if m, ok := r.findIndexMapList(name, r.normalize(v)); ok {
+		m.Range(func(k string, v any) bool {
+			result, found = r.lookup(k)
+			return !found
+		})
+	}
+	return result, found
+}
+
+// Find at most n elements by index value, stopping as soon as they are collected,
+// so huge index values don't cost more than small ones. Which elements are returned is arbitrary.
+// Returns empty slice if n isn't positive.
+func (r *IndexedMap[T]) GetByIndexFirstN(name string, v string, n int) []T {
+	t := r.mu.RLock()
+	defer r.mu.RUnlock(t)
+	result := []T{}
+	if n <= 0 {
+		return result
+	}
+	m, ok := r.findIndexMapList(name, r.normalize(v))
+	if !ok {
+		return result
+	}
+	result = make([]T, 0, min(n, m.Size()))
+	m.Range(func(k string, _ any) bool {
+		if obj, ok := r.lookup(k); ok {
+			result = append(result, obj)
+		}
+		return len(result) < n
+	})
+	return result
+}
+
+// Find element by primary key, falling back to GetOneByIndex if the key is missing,
+// e.g. to look up an entity by id or by its unique code.
+// If index isn't unique, an arbitrary element having the index value is returned.
+func (r *IndexedMap[T]) GetByKeyOrIndex(key string, name string, v string) (T, bool) {
+	if obj, ok := r.Get(key); ok {
+		return obj, true
+	}
+	return r.GetOneByIndex(name, v)
+}
+
+// Check if any element has index value.
+func (r *IndexedMap[T]) ContainsIndexValue(name string, v string) bool {
+	t := r.mu.RLock()
+	defer r.mu.RUnlock(t)
+	found := false
+	if m, ok := r.findIndexMapList(name, r.normalize(v)); ok {
+		m.Range(func(k string, v any) bool {
+			found = !r.expired(k)
+			return !found
+		})
+	}
+	return found
+}
+
+// Find all elements having any of index values.
+// Element matching several values is returned once.
+func (r *IndexedMap[T]) GetByIndexAny(name string, values ...string) []T {
+	t := r.mu.RLock()
+	defer r.mu.RUnlock(t)
+	normalized := make([]string, 0, len(values))
+	for _, v := range values {
+		normalized = append(normalized, r.normalize(v))
+	}
+	return r.collectByIndexValues(name, normalized)
+}
+
+// Collect elements having any of normalized index values, each element once.
+func (r *IndexedMap[T]) collectByIndexValues(name string, values []string) []T {
+	result := []T{}
+	seen := map[string]struct{}{}
+	for _, value := range values {
+		m, ok := r.findIndexMapList(name, value)
+		if !ok {
+			continue
+		}
+		m.Range(func(k string, _ any) bool {
+			if _, ok := seen[k]; !ok {
+				seen[k] = struct{}{}
+				if obj, ok := r.lookup(k); ok {
+					result = append(result, obj)
+				}
+			}
+			return true
+		})
+	}
+	return result
+}
+
+// Find index values containing substring.
+// Not backed by an index: all distinct values are scanned, so it's O(number of index values).
+func (r *IndexedMap[T]) GetByIndexValuesContaining(name string, substr string) []string {
+	t := r.mu.RLock()
+	defer r.mu.RUnlock(t)
+	return r.valuesContaining(name, r.normalize(substr))
+}
+
+// Find elements having index value containing substring.
+// Element matching several values is returned once, see GetByIndexValuesContaining for complexity.
+func (r *IndexedMap[T]) GetByIndexContaining(name string, substr string) []T {
+	t := r.mu.RLock()
+	defer r.mu.RUnlock(t)
+	return r.collectByIndexValues(name, r.valuesContaining(name, r.normalize(substr)))
+}
+
+func (r *IndexedMap[T]) valuesContaining(name string, substr string) []string {
+	result := []string{}
+	index, ok := r.secondary[name]
+	if !ok {
+		return result
+	}
+	index.Range(func(k string, v any) bool {
+		if v.(*xsync.Map).Size() > 0 && strings.Contains(k, substr) {
+			result = append(result, k)
+		}
+		return true
+	})
+	return result
+}
+
+// Count elements by index value without copying them.
+func (r *IndexedMap[T]) CountByIndex(name string, v string) int {
+	t := r.mu.RLock()
+	defer r.mu.RUnlock(t)
+	if m, ok := r.findIndexMapList(name, r.normalize(v)); ok {
+		return r.countLive(m)
+	}
+	return 0
+}
+
+// Count keys of index value collection skipping expired elements.
+// It's O(1) for maps without TTL and ranges the collection otherwise.
+func (r *IndexedMap[T]) countLive(m *xsync.Map) int {
+	if r.expiry.Load() == nil {
+		return m.Size()
+	}
+	n := 0
+	m.Range(func(k string, _ any) bool {
+		if !r.expired(k) {
+			n++
+		}
+		return true
+	})
+	return n
+}
+
+// Find elements matching all index name and value pairs.
+// Intersection starts from the smallest index value collection.
+// Returns empty result if any of values is missing or index is unknown.
+func (r *IndexedMap[T]) GetByIndexes(pairs map[string]string) []T {
+	t := r.mu.RLock()
+	defer r.mu.RUnlock(t)
+	result := []T{}
+	lists := make([]*xsync.Map, 0, len(pairs))
+	for name, v := range pairs {
+		m, ok := r.findIndexMapList(name, r.normalize(v))
+		if !ok {
+			return result
+		}
+		lists = append(lists, m)
+	}
+	if len(lists) == 0 {
+		return result
+	}
+	slices.SortFunc(lists, func(a, b *xsync.Map) int {
+		return a.Size() - b.Size()
+	})
+	lists[0].Range(func(k string, v any) bool {
+		for _, m := range lists[1:] {
+			if _, ok := m.Load(k); !ok {
+				return true
+			}
+		}
+		if obj, ok := r.lookup(k); ok {
+			result = append(result, obj)
+		}
+		return true
+	})
+	return result
+}
+
+// Find elements having valueA of index nameA and valueB of index nameB, each element once.
+// Missing index values and unknown indexes are treated as empty sets.
+func (r *IndexedMap[T]) IntersectByIndex(nameA, valueA, nameB, valueB string) []T {
+	return r.combineByIndex(nameA, valueA, nameB, valueB, true)
+}
+
+// Find elements having valueA of index nameA, but not valueB of index nameB, each element once,
+// e.g. Type=big animals which are not Role=prey.
+// Missing index values and unknown indexes are treated as empty sets.
+func (r *IndexedMap[T]) DifferenceByIndex(nameA, valueA, nameB, valueB string) []T {
+	return r.combineByIndex(nameA, valueA, nameB, valueB, false)
+}
+
+// Resolve keys of valueA collection which are (or are not, if inB is false) in valueB collection.
+func (r *IndexedMap[T]) combineByIndex(nameA, valueA, nameB, valueB string, inB bool) []T {
+	t := r.mu.RLock()
+	defer r.mu.RUnlock(t)
+	a := r.indexKeySet(nameA, valueA)
+	b := r.indexKeySet(nameB, valueB)
+	result := make([]T, 0, len(a))
+	for k := range a {
+		if _, ok := b[k]; ok != inB {
+			continue
+		}
+		if obj, ok := r.lookup(k); ok {
+			result = append(result, obj)
+		}
+	}
+	return result
+}
+
+// Get primary keys of index value collection as a set, empty if value or index is missing.
+func (r *IndexedMap[T]) indexKeySet(name string, v string) map[string]struct{} {
+	keys := map[string]struct{}{}
+	if m, ok := r.findIndexMapList(name, r.normalize(v)); ok {
+		m.Range(func(k string, _ any) bool {
+			keys[k] = struct{}{}
+			return true
+		})
+	}
+	return keys
+}
+
+// Find all elements having any value of index, each element once.
+// Unlike Values, elements for which index function returns only empty values are not included,
+// because empty values are not indexed. See GetUnindexed for them.
+func (r *IndexedMap[T]) GetAllIndexed(name string) []T {
+	t := r.mu.RLock()
+	defer r.mu.RUnlock(t)
+	return r.collectByIndexValues(name, r.indexKeys(name))
+}
+
+// Find elements which have no value of index, because empty values are not indexed.
+// Not backed by an index: index function is computed for every element of primary index.
+// Returns empty slice for unknown index.
+func (r *IndexedMap[T]) GetUnindexed(name string) []T {
+	t := r.mu.RLock()
+	defer r.mu.RUnlock(t)
+	result := []T{}
+	if _, ok := r.indexes[name]; !ok {
+		return result
+	}
+	r.ForEach(func(key string, value T) bool {
+		if len(r.indexValues(name, &value)) == 0 {
+			result = append(result, value)
+		}
+		return true
+	})
+	return result
+}
+
+// Get all values for specified index.
+func (r *IndexedMap[T]) GetIndexKeys(name string) []string {
+	t := r.mu.RLock()
+	defer r.mu.RUnlock(t)
+	return r.indexKeys(name)
+}
+
+func (r *IndexedMap[T]) indexKeys(name string) []string {
+	result := []string{}
+	index, ok := r.secondary[name]
+	if !ok {
+		return result
+	}
+	index.Range(func(k string, v any) bool {
+		result = append(result, k)
+		return true
+	})
+	return result
+}
+
+// Get underlying sync.Map for selected index and value.
+// It holds primary keys of elements having the value, elements themselves are kept in primary index only.
+// For missing index value returns empty map which is not attached to the index.
+func (r *IndexedMap[T]) GetByIndexUnderlyingMap(name string, v string) *xsync.Map {
+	t := r.mu.RLock()
+	defer r.mu.RUnlock(t)
+	indexValue := r.normalize(v)
+	if m, ok := r.findIndexMapList(name, indexValue); ok {
+		return m
+	}
+	return xsync.NewMap()
+}
+
+// Get underlying sync.Map for primary index.
+// Values are of type T.
+func (r *IndexedMap[T]) GetPrimaryIndexUnderlyingMap() *xsync.Map {
+	return r.primary
+}
+
+// Count elements in the indexed map.
+// Same as ApproxSize, minus elements which expired but are not removed yet.
+// Expired elements are counted as their deadlines pass, so it stays O(1) amortized for maps with TTL.
+func (r *IndexedMap[T]) Size() int {
+	return max(0, r.ApproxSize()-r.expiredCount())
+}
+
+// Count elements in the indexed map using counter maintained on Put and Remove, so it's O(1).
+// The counter is updated right after primary index, so during concurrent writes
+// it may be momentarily off by the number of writes in flight. It's exact when there are no writers.
+func (r *IndexedMap[T]) ApproxSize() int {
+	return int(r.size.Load())
+}
+
+// Count elements by ranging primary index, it's O(n).
+// Result is exact only if writes are paused, concurrent writes made during the count
+// may or may not be included. Clear never interleaves with the count.
+// Expired elements are skipped, so it matches Size when there are no writers.
+func (r *IndexedMap[T]) ExactSize() int {
+	t := r.mu.RLock()
+	defer r.mu.RUnlock(t)
+	n := 0
+	r.primary.Range(func(k string, _ any) bool {
+		if !r.expired(k) {
+			n++
+		}
+		return true
+	})
+	return n
+}
+
+// Remove all elements from primary and secondary indexes.
+// Index configuration is preserved, so the map can be repopulated right away.
+// Concurrent readers observe either the state before Clear or the empty map, never a partially cleared one.
+// Underlying maps are cleared in place and stay valid for the callers holding them.
+// Change listeners get single OpClear event.
+func (r *IndexedMap[T]) Clear() {
+	r.checkOpen()
+	r.withLock(func() {
+		r.primary.Clear()
+		r.origKeys.Clear()
+		for _, m := range r.secondary {
+			m.Clear()
+		}
+		for _, s := range r.ordered {
+			s.clear()
+		}
+		if e := r.expiry.Load(); e != nil {
+			e.clear()
+		}
+		if r.lru != nil {
+			r.lru.clear()
+		}
+		r.size.Store(0)
+	})
+	r.notify(ChangeEvent[T]{Op: OpClear})
+}
+
+// Check if the indexed map has no elements.
+func (r *IndexedMap[T]) IsEmpty() bool {
+	return r.Size() == 0
+}
+
+// Create independent copy of the map with the same index configuration.
+// Elements are copied by value from primary index and secondary indexes are rebuilt,
+// so every element found in the copy's secondary indexes is present in its primary index.
+// Since writers are not blocked, the copy reflects loosely consistent moment of the source map.
+// Changes of either map after the call don't affect the other one.
+func (r *IndexedMap[T]) Snapshot() *IndexedMap[T] {
+	return r.clone()
+}
+
+// Create independent copy of the map to be changed separately from the source, e.g. for what-if analysis.
+// Elements are copied by value like in Snapshot, so data referenced by their
+// pointer, slice or map fields is still shared with the source map.
+// Expiration deadlines of elements put with TTL are preserved.
+func (r *IndexedMap[T]) Clone() *IndexedMap[T] {
+	return r.clone()
+}
+
+func (r *IndexedMap[T]) clone() *IndexedMap[T] {
+	t := r.mu.RLock()
+	n := newIndexedMap(maps.Clone(r.indexes), r.options())
+	r.mu.RUnlock(t)
+	if r.expiry.Load() != nil {
+		n.expiry.Store(newExpiryIndex())
+	}
+	r.primary.Range(func(k string, v any) bool {
+		n.putExpiring(k, r.originalKey(k), r.unbox(v), r.deadline(k))
+		return true
+	})
+	return n
+}
+
+// Check that both maps hold the same primary keys with elements equal by eq.
+// Only primary indexes are compared, secondary indexes and options may differ.
+// Keys of this map are looked up in other one with its own normalization.
+// Like ForEach, it doesn't correspond to a consistent snapshot of maps changed concurrently,
+// and expired elements are skipped in both maps.
+func (r *IndexedMap[T]) Equal(other *IndexedMap[T], eq func(a, b T) bool) bool {
+	n := 0
+	equal := true
+	r.ForEach(func(k string, v T) bool {
+		n++
+		o, ok := other.lookup(other.normalize(k))
+		equal = ok && eq(v, o)
+		return equal
+	})
+	if !equal {
+		return false
+	}
+	m := 0
+	other.ForEach(func(string, T) bool {
+		m++
+		return true
+	})
+	return n == m
+}
+
+// Register new secondary index and build it from elements already stored in the map.
+// Returns false if index with this name already exists.
+// Elements put concurrently with the build are indexed correctly once AddIndex returns.
+func (r *IndexedMap[T]) AddIndex(name string, fn IndexFunc[T]) bool {
+	return r.AddIndexMulti(name, singleValue(fn))
+}
+
+// Register new secondary index which may exclude elements, see AddIndex.
+func (r *IndexedMap[T]) AddIndexOpt(name string, fn IndexFuncOpt[T]) bool {
+	return r.AddIndexMulti(name, optValue(fn))
+}
+
+// Register new multi-value secondary index, see AddIndex.
+func (r *IndexedMap[T]) AddIndexMulti(name string, fn IndexFuncMulti[T]) bool {
+	r.checkOpen()
+	added := false
+	r.withLock(func() {
+		if _, ok := r.indexes[name]; ok {
+			return
+		}
+		r.indexes[name] = fn
+		r.secondary[name] = xsync.NewMap()
+		added = true
+	})
+	if !added {
+		return false
+	}
+
+	// Writers started after registration maintain the new index themselves.
+	// Backfill goes under primary key lock, so it can't override their changes with stale values.
+	t := r.mu.RLock()
+	defer r.mu.RUnlock(t)
+	r.primary.Range(func(k string, v any) bool {
+		// expired elements are indexed too, as they stay in other indexes until removed
+		r.primary.Compute(k, func(old any, loaded bool) (any, bool) {
+			if loaded {
+				obj := r.pointer(old)
+				for _, v := range r.indexValues(name, obj) {
+					r.putToIndex(name, v, k)
+				}
+			}
+			return old, !loaded
+		})
+		return true
+	})
+	return true
+}
+
+// Get sorted names of registered secondary indexes, including ones added by AddIndex.
+func (r *IndexedMap[T]) IndexNames() []string {
+	t := r.mu.RLock()
+	defer r.mu.RUnlock(t)
+	return r.indexNames()
+}
+
+// Get sorted names of secondary indexes.
+func (r *IndexedMap[T]) indexNames() []string {
+	names := make([]string, 0, len(r.indexes))
+	for name := range r.indexes {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// Check if secondary index with this name is registered.
+func (r *IndexedMap[T]) HasIndex(name string) bool {
+	t := r.mu.RLock()
+	defer r.mu.RUnlock(t)
+	_, ok := r.indexes[name]
+	return ok
+}
+
+// Rebuild all secondary indexes from elements of primary index, e.g. to recover from index function change.
+// Result is the same as putting every element to empty map again.
+// Other operations are blocked while rebuild is in progress, so they observe either old or rebuilt indexes.
+func (r *IndexedMap[T]) Reindex() {
+	r.checkOpen()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, m := range r.secondary {
+		m.Clear()
+	}
+	for _, s := range r.ordered {
+		s.clear()
+	}
+	r.primary.Range(func(k string, v any) bool {
+		obj := r.pointer(v)
+		for index := range r.indexes {
+			for _, iv := range r.indexValues(index, obj) {
+				r.putToIndex(index, iv, k)
+			}
+		}
+		return true
+	})
+}
+
+// Replace index function of registered index and rebuild only this index from primary index.
+// Returns ErrIndexNotFound if there is no index with this name.
+// For unique index returns ErrUniqueViolation if new function gives the same value to elements with different keys,
+// old function is kept then.
+// Like Reindex, other operations are blocked during rebuild, so readers observe either old or rebuilt index.
+func (r *IndexedMap[T]) SetIndexFunc(name string, fn IndexFunc[T]) error {
+	return r.SetIndexFuncMulti(name, singleValue(fn))
+}
+
+// Replace index function with multi-value one, see SetIndexFunc.
+func (r *IndexedMap[T]) SetIndexFuncMulti(name string, fn IndexFuncMulti[T]) error {
+	r.checkOpen()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.indexes[name]; !ok {
+		return fmt.Errorf("%w: %s", ErrIndexNotFound, name)
+	}
+	old := r.indexes[name]
+	r.indexes[name] = fn
+	if slices.Contains(r.unique, name) {
+		if err := r.checkUniqueRebuild(name); err != nil {
+			r.indexes[name] = old
+			return err
+		}
+	}
+	r.secondary[name].Clear()
+	if s, ok := r.ordered[name]; ok {
+		s.clear()
+	}
+	r.primary.Range(func(k string, v any) bool {
+		for _, iv := range r.indexValues(name, r.pointer(v)) {
+			r.putToIndex(name, iv, k)
+		}
+		return true
+	})
+	return nil
+}
+
+// Check that index function gives unique values to elements of primary index, must be called under exclusive lock.
+// Expired elements are skipped, as they don't hold unique values.
+func (r *IndexedMap[T]) checkUniqueRebuild(name string) error {
+	owners := map[string]string{}
+	var err error
+	r.primary.Range(func(k string, v any) bool {
+		if r.expired(k) {
+			return true
+		}
+		for _, iv := range r.indexValues(name, r.pointer(v)) {
+			if _, ok := owners[iv]; ok {
+				err = fmt.Errorf("%w: %s=%s", ErrUniqueViolation, name, iv)
+				return false
+			}
+			owners[iv] = k
+		}
+		return true
+	})
+	return err
+}
+
+// Drop secondary index and release its memory.
+// Returns false if there is no index with this name.
+// Afterwards the name behaves like unknown index: lookups by it return empty results.
+func (r *IndexedMap[T]) RemoveIndex(name string) bool {
+	r.checkOpen()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.indexes[name]; !ok {
+		return false
+	}
+	delete(r.indexes, name)
+	delete(r.secondary, name)
+	delete(r.ordered, name)
+	r.unique = slices.DeleteFunc(r.unique, func(n string) bool { return n == name })
+	return true
+}
+
+// Options reproducing current configuration of the map.
+func (r *IndexedMap[T]) options() Options[T] {
+	opts := r.opts
+	opts.UniqueIndexes = slices.Clone(r.unique)
+	opts.OrderedIndexes = make([]string, 0, len(r.ordered))
+	for name := range r.ordered {
+		opts.OrderedIndexes = append(opts.OrderedIndexes, name)
+	}
+	return opts
 }
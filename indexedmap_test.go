@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"runtime"
 	"strconv"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"testing"
@@ -17,6 +18,7 @@ type Person struct {
 	FirstName string
 	LastName  string
 	SSN       string
+	Tags      []string
 }
 
 // method define indexing key
@@ -162,6 +164,30 @@ func TestPutSame(t *testing.T) {
 	assert.Equal(t, 1, s, "Wrong map size")
 }
 
+func TestPutSameEquals(t *testing.T) {
+	calls := 0
+	m := NewIndexedMapWithOptions(map[string]IndexFunc[Animal]{
+		"Type": func(a *Animal) string {
+			calls++
+			return a.Type
+		},
+	}, Options[Animal]{Equals: func(a, b Animal) bool { return a == b }})
+
+	x := Animal{Id: 1, Name: "Dog", Type: "big"}
+	m.PutInt(x.Id, x)
+	assert.Equal(t, 1, calls)
+
+	m.PutInt(x.Id, x)
+	m.PutInt(x.Id, x)
+	assert.Equal(t, 1, calls)
+	assert.Equal(t, 1, len(m.GetByIndex("Type", "big")))
+
+	x.Type = "small"
+	m.PutInt(x.Id, x)
+	assert.Equal(t, 0, len(m.GetByIndex("Type", "big")))
+	assert.Equal(t, 1, len(m.GetByIndex("Type", "small")))
+}
+
 func TestRemove(t *testing.T) {
 	m := NewAnimalMap()
 
@@ -362,6 +388,26 @@ func TestRemoveFromIndex(t *testing.T) {
 	assert.Equal(t, 1, len(m.GetByIndex("Type", "two")))
 }
 
+func TestGetByIndexReflectsUpdate(t *testing.T) {
+	m := NewAnimalMap()
+
+	m.PutInt(1, Animal{Id: 1, Name: "Cat", Type: "small"})
+	m.PutInt(1, Animal{Id: 1, Name: "Kitten", Type: "small"})
+
+	list := m.GetByIndex("Type", "small")
+	assert.Equal(t, 1, len(list))
+	assert.Equal(t, "Kitten", list[0].Name)
+
+	m.Update("1", func(a *Animal) bool {
+		a.Name = "Tiger"
+		return true
+	})
+	a, _ := m.GetOneByIndex("Type", "small")
+	assert.Equal(t, "Tiger", a.Name)
+	b, _ := m.GetInt(1)
+	assert.Equal(t, a, b)
+}
+
 func TestKeyChange(t *testing.T) {
 	m := NewAnimalMap()
 
@@ -384,6 +430,59 @@ func TestKeyChange(t *testing.T) {
 	assert.Equal(t, 2, len(m.GetByIndex("Type", "big")))
 }
 
+func TestConcurrentKeyChangeConsistent(t *testing.T) {
+	m := NewAnimalMap()
+
+	m.PutInt(1, Animal{Id: 1, Name: "Rabbit", Type: "small"})
+	m.PutInt(2, Animal{Id: 2, Name: "Mouse", Type: "small"})
+
+	count := 20000
+	var wg sync.WaitGroup
+	wg.Add(2)
+
+	go func() {
+		defer wg.Done()
+		for i := range count {
+			typ := "small"
+			if i%2 == 0 {
+				typ = "big"
+			}
+			m.PutInt(1, Animal{Id: 1, Name: "Rabbit", Type: typ})
+		}
+	}()
+
+	var missing, stale atomic.Uint64
+	go func() {
+		defer wg.Done()
+		for range count {
+			found := false
+			for _, a := range m.GetByIndexConsistent("Type", "small") {
+				if a.Type != "small" {
+					stale.Add(1)
+				}
+				if a.Id == 2 {
+					found = true
+				}
+			}
+			if !found {
+				missing.Add(1)
+			}
+			for _, a := range m.GetByIndexConsistent("Type", "big") {
+				if a.Type != "big" {
+					stale.Add(1)
+				}
+			}
+		}
+	}()
+
+	wg.Wait()
+
+	assert.Equal(t, uint64(0), missing.Load())
+	assert.Equal(t, uint64(0), stale.Load())
+	assert.Equal(t, 2, len(m.GetByIndexConsistent("Type", "small")))
+	assert.Equal(t, 0, len(m.GetByIndexConsistent("Type", "huge")))
+}
+
 func TestPutAll(t *testing.T) {
 	m := NewAnimalMap()
 
@@ -404,6 +503,49 @@ func TestPutAll(t *testing.T) {
 	fmt.Printf("Data added to index %v\n", time.Since(ts))
 }
 
+func TestPutAllCopies(t *testing.T) {
+	for _, count := range []int{100, 20001} {
+		m := NewAnimalMap()
+
+		data := make([]Animal, 0, count)
+		for i := range count {
+			data = append(data, Animal{Id: i, Name: "animal" + strconv.Itoa(i), Type: "small"})
+		}
+		m.PutAll(data, func(a *Animal) string { return strconv.Itoa(a.Id) })
+
+		for i := range data {
+			data[i].Name = "changed"
+			data[i].Type = "big"
+		}
+
+		a, _ := m.GetInt(count - 1)
+		assert.Equal(t, "animal"+strconv.Itoa(count-1), a.Name)
+		assert.Equal(t, count, len(m.GetByIndex("Type", "small")))
+		for _, a := range m.GetByIndex("Type", "small") {
+			assert.Equal(t, "small", a.Type)
+			assert.NotEqual(t, "changed", a.Name)
+		}
+	}
+}
+
+func TestPutAllMap(t *testing.T) {
+	for _, count := range []int{100, 20001} {
+		m := NewAnimalMap()
+
+		data := make(map[string]Animal, count)
+		for i := range count {
+			data["a"+strconv.Itoa(i)] = Animal{Id: i, Name: "animal" + strconv.Itoa(i), Type: "t" + strconv.Itoa(i%2)}
+		}
+
+		m.PutAllMap(data)
+		assert.Equal(t, count, m.Size())
+		assert.Equal(t, count/2, len(m.GetByIndex("Type", "t1")))
+		a, ok := m.Get("A7")
+		assert.True(t, ok)
+		assert.Equal(t, 7, a.Id)
+	}
+}
+
 func TestKeyCase(t *testing.T) {
 	m := NewAnimalMap()
 
@@ -416,3 +558,1747 @@ func TestKeyCase(t *testing.T) {
 	assert.True(t, ok)
 
 }
+
+func TestKeyOriginalCase(t *testing.T) {
+	m := NewAnimalMap()
+
+	m.Put("test", Animal{Id: 1, Name: "Dog", Type: "big"})
+	assert.Equal(t, []string{"test"}, m.Keys())
+
+	m.Put("Test", Animal{Id: 1, Name: "Dog", Type: "small"})
+	assert.Equal(t, []string{"Test"}, m.Keys())
+	m.ForEach(func(key string, value Animal) bool {
+		assert.Equal(t, "Test", key)
+		return true
+	})
+
+	s := m.Snapshot()
+	assert.Equal(t, []string{"Test"}, s.Keys())
+
+	_, ok := m.Remove("TEST")
+	assert.True(t, ok)
+	m.PutIfAbsent("TEST", Animal{Id: 1, Name: "Dog", Type: "big"})
+	assert.Equal(t, []string{"TEST"}, m.Keys())
+	assert.Equal(t, 0, m.origKeys.Size())
+}
+
+func TestContainsKeys(t *testing.T) {
+	m := NewAnimalMap()
+
+	m.Put("cat", Animal{Id: 1, Name: "Cat", Type: "small"})
+	m.Put("dog", Animal{Id: 2, Name: "Dog", Type: "small"})
+
+	assert.True(t, m.ContainsAllKeys("Cat", "DOG"))
+	assert.False(t, m.ContainsAllKeys("cat", "cow"))
+	assert.True(t, m.ContainsAllKeys())
+
+	assert.True(t, m.ContainsAnyKeys("cow", "CAT"))
+	assert.False(t, m.ContainsAnyKeys("cow", "pig"))
+	assert.False(t, m.ContainsAnyKeys())
+}
+
+func TestClear(t *testing.T) {
+	m := NewAnimalMap()
+
+	m.PutInt(1, Animal{Id: 1, Name: "Cat", Type: "small", Role: "pet"})
+	m.PutInt(2, Animal{Id: 2, Name: "Cow", Type: "big", Role: "farm"})
+
+	m.Clear()
+
+	assert.Equal(t, 0, m.Size())
+	assert.Equal(t, 0, len(m.Keys()))
+	assert.Equal(t, 0, len(m.GetIndexKeys("Type")))
+	assert.Equal(t, 0, len(m.GetIndexKeys("Role")))
+	assert.False(t, m.ContainsKeyInt(1))
+
+	m.PutInt(3, Animal{Id: 3, Name: "Dog", Type: "small"})
+
+	assert.Equal(t, 1, m.Size())
+	assert.Equal(t, 1, len(m.GetByIndex("Type", "small")))
+}
+
+func TestSize(t *testing.T) {
+	m := NewAnimalMap()
+
+	m.PutInt(1, Animal{Id: 1, Name: "Cat", Type: "small"})
+	m.PutInt(2, Animal{Id: 2, Name: "Dog", Type: "small"})
+	m.PutInt(1, Animal{Id: 1, Name: "Cat", Type: "big"})
+	assert.Equal(t, 2, m.Size())
+
+	m.RemoveInt(2)
+	m.RemoveInt(2)
+	m.RemoveInt(3)
+	assert.Equal(t, 1, m.Size())
+
+	var wg sync.WaitGroup
+	for range 50 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range 1000 {
+				m.PutInt(i, Animal{Id: i, Name: "animal" + strconv.Itoa(i)})
+			}
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, 1000, m.Size())
+	assert.Equal(t, 1000, m.ApproxSize())
+	assert.Equal(t, 1000, m.ExactSize())
+
+	for range 50 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range 500 {
+				m.RemoveInt(i)
+			}
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, 500, m.Size())
+	assert.Equal(t, 500, m.ExactSize())
+	assert.Equal(t, 500, len(m.Keys()))
+
+	m.Clear()
+	assert.Equal(t, 0, m.ApproxSize())
+	assert.Equal(t, 0, m.ExactSize())
+}
+
+func TestSizeInvariants(t *testing.T) {
+	tests := []struct {
+		name    string
+		inserts int
+		dups    int
+		removes int
+		missing int
+		clear   bool
+		size    int
+	}{
+		{name: "inserts", inserts: 10, size: 10},
+		{name: "duplicate puts", inserts: 10, dups: 10, size: 10},
+		{name: "removes of existing keys", inserts: 10, removes: 4, size: 6},
+		{name: "removes of missing keys", inserts: 10, missing: 5, size: 10},
+		{name: "mixed", inserts: 12, dups: 5, removes: 7, missing: 3, size: 5},
+		{name: "clear", inserts: 10, dups: 3, removes: 2, clear: true, size: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := NewAnimalMap()
+			for i := range tt.inserts {
+				m.PutInt(i, Animal{Id: i, Type: "small"})
+			}
+			for i := range tt.dups {
+				m.PutInt(i, Animal{Id: i, Type: "big"})
+			}
+			for i := range tt.removes {
+				m.RemoveInt(i)
+			}
+			for i := range tt.missing {
+				m.RemoveInt(1000 + i)
+			}
+			if tt.clear {
+				m.Clear()
+			}
+			assert.Equal(t, tt.size, m.Size())
+			assert.Equal(t, tt.size, m.ExactSize())
+			assert.Equal(t, tt.size, len(m.Keys()))
+		})
+	}
+}
+
+func TestSizeConcurrentDuplicates(t *testing.T) {
+	m := NewAnimalMap()
+
+	var wg sync.WaitGroup
+	for g := range 20 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range 50 {
+				m.PutInt(i, Animal{Id: i, Name: strconv.Itoa(g)})
+				m.PutIfAbsent(strconv.Itoa(i), Animal{Id: i})
+				m.ComputeIfAbsent(strconv.Itoa(i), func() Animal { return Animal{Id: i} })
+			}
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, 50, m.Size())
+	assert.Equal(t, 50, m.ExactSize())
+}
+
+func TestIsEmpty(t *testing.T) {
+	m := NewAnimalMap()
+	assert.True(t, m.IsEmpty())
+
+	m.PutInt(1, Animal{Id: 1, Name: "Cat"})
+	assert.False(t, m.IsEmpty())
+
+	m.RemoveInt(1)
+	assert.True(t, m.IsEmpty())
+
+	m.PutInt(1, Animal{Id: 1, Name: "Cat"})
+	m.Clear()
+	assert.True(t, m.IsEmpty())
+}
+
+func NewTaggedPersonMap() *IndexedMap[Person] {
+	return NewIndexedMapMulti(map[string]IndexFunc[Person]{
+		"LastName": func(r *Person) string {
+			return r.LastName
+		},
+	}, map[string]IndexFuncMulti[Person]{
+		"Tag": func(r *Person) []string {
+			return r.Tags
+		},
+	})
+}
+
+func TestMultiValueIndex(t *testing.T) {
+	m := NewTaggedPersonMap()
+
+	alex := Person{Id: 1, FirstName: "Alex", LastName: "Smith", Tags: []string{"admin", "dev", "admin"}}
+	john := Person{Id: 2, FirstName: "John", LastName: "Doe", Tags: []string{"dev"}}
+	jane := Person{Id: 3, FirstName: "Jane", LastName: "Doe"}
+
+	m.PutInt(alex.Id, alex)
+	m.PutInt(john.Id, john)
+	m.PutInt(jane.Id, jane)
+
+	assert.Equal(t, 1, len(m.GetByIndex("Tag", "admin")))
+	assert.Equal(t, 2, len(m.GetByIndex("Tag", "DEV")))
+	assert.Equal(t, 2, len(m.GetIndexKeys("Tag")))
+	assert.Equal(t, 2, len(m.GetByIndex("LastName", "doe")))
+
+	alex.Tags = []string{"dev", "ops"}
+	m.PutInt(alex.Id, alex)
+
+	assert.Equal(t, 0, len(m.GetByIndex("Tag", "admin")))
+	assert.Equal(t, 2, len(m.GetByIndex("Tag", "dev")))
+	assert.Equal(t, 1, len(m.GetByIndex("Tag", "ops")))
+
+	m.RemoveInt(alex.Id)
+
+	assert.Equal(t, 1, len(m.GetByIndex("Tag", "dev")))
+	assert.Equal(t, 0, len(m.GetByIndex("Tag", "ops")))
+}
+
+func TestMultiValueNormalization(t *testing.T) {
+	m := NewTaggedPersonMap()
+
+	alex := Person{Id: 1, Tags: []string{"dev", "", "Dev", "ops", "DEV"}}
+	m.PutInt(alex.Id, alex)
+
+	assert.Equal(t, []string{"DEV", "OPS"}, m.GetIndexKeysSorted("Tag"))
+	assert.Equal(t, 1, len(m.GetByIndex("Tag", "dev")))
+	assert.Equal(t, 1, m.CountByIndex("Tag", "dev"))
+
+	alex.Tags = []string{"ops", "admin", "Admin"}
+	m.PutInt(alex.Id, alex)
+
+	assert.Equal(t, 0, len(m.GetByIndex("Tag", "dev")))
+	assert.Equal(t, 1, len(m.GetByIndex("Tag", "ops")))
+	assert.Equal(t, 1, len(m.GetByIndex("Tag", "admin")))
+	assert.Nil(t, m.Validate())
+
+	assert.Equal(t, []string{"A", "C"}, sortedDiff([]string{"A", "B", "C"}, []string{"B", "D"}))
+	assert.Nil(t, sortedDiff([]string{"A"}, []string{"A"}))
+}
+
+func TestOptIndex(t *testing.T) {
+	m := NewIndexedMapWithOptions(map[string]IndexFunc[Animal]{
+		"Type": func(a *Animal) string {
+			return a.Type
+		},
+	}, Options[Animal]{OptIndexes: map[string]IndexFuncOpt[Animal]{
+		"PetName": func(a *Animal) (string, bool) {
+			return a.Name, a.Role == "pet"
+		},
+	}})
+
+	m.PutInt(1, Animal{Id: 1, Name: "Cat", Type: "small", Role: "pet"})
+	m.PutInt(2, Animal{Id: 2, Name: "Cow", Type: "big", Role: "food"})
+	assert.Equal(t, 1, len(m.GetByIndex("PetName", "cat")))
+	assert.Equal(t, 0, len(m.GetByIndex("PetName", "cow")))
+	assert.Equal(t, []string{"CAT"}, m.GetIndexKeys("PetName"))
+
+	m.PutInt(1, Animal{Id: 1, Name: "Cat", Type: "small", Role: "wild"})
+	assert.Equal(t, 0, len(m.GetByIndex("PetName", "cat")))
+	m.PutInt(2, Animal{Id: 2, Name: "Cow", Type: "big", Role: "pet"})
+	assert.Equal(t, 1, len(m.GetByIndex("PetName", "cow")))
+
+	assert.True(t, m.AddIndexOpt("WildType", func(a *Animal) (string, bool) {
+		return a.Type, a.Role == "wild"
+	}))
+	assert.Equal(t, 1, len(m.GetByIndex("WildType", "small")))
+	assert.Equal(t, 0, len(m.GetByIndex("WildType", "big")))
+
+	m.RemoveInt(2)
+	assert.Equal(t, 0, len(m.GetByIndex("PetName", "cow")))
+	assert.Nil(t, m.Validate())
+}
+
+func TestIndexValueOf(t *testing.T) {
+	m := NewTaggedPersonMap()
+
+	m.Put("alex", Person{Id: 1, LastName: "Smith", Tags: []string{"ops", "dev"}})
+	m.Put("jane", Person{Id: 2})
+
+	v, ok := m.IndexValueOf("LastName", "Alex")
+	assert.True(t, ok)
+	assert.Equal(t, "SMITH", v)
+
+	v, ok = m.IndexValueOf("Tag", "alex")
+	assert.True(t, ok)
+	assert.Equal(t, "DEV", v)
+	values, ok := m.IndexValuesOf("Tag", "alex")
+	assert.True(t, ok)
+	assert.Equal(t, []string{"DEV", "OPS"}, values)
+
+	v, ok = m.IndexValueOf("LastName", "jane")
+	assert.True(t, ok)
+	assert.Equal(t, "", v)
+
+	_, ok = m.IndexValueOf("LastName", "john")
+	assert.False(t, ok)
+	_, ok = m.IndexValueOf("Color", "alex")
+	assert.False(t, ok)
+}
+
+func TestGetByIndexAny(t *testing.T) {
+	m := NewAnimalMap()
+
+	m.PutInt(1, Animal{Id: 1, Name: "Cow", Type: "big"})
+	m.PutInt(2, Animal{Id: 2, Name: "Whale", Type: "huge"})
+	m.PutInt(3, Animal{Id: 3, Name: "Cat", Type: "small"})
+
+	assert.Equal(t, 2, len(m.GetByIndexAny("Type", "Big", "HUGE")))
+	assert.Equal(t, 1, len(m.GetByIndexAny("Type", "big", "big")))
+	assert.NotNil(t, m.GetByIndexAny("Type", "tiny"))
+	assert.Equal(t, 0, len(m.GetByIndexAny("Type")))
+
+	p := NewTaggedPersonMap()
+	p.PutInt(1, Person{Id: 1, Tags: []string{"admin", "dev"}})
+	p.PutInt(2, Person{Id: 2, Tags: []string{"dev"}})
+
+	assert.Equal(t, 2, len(p.GetByIndexAny("Tag", "admin", "dev")))
+}
+
+func TestGetByIndexContaining(t *testing.T) {
+	m := NewAnimalMap()
+
+	m.PutInt(1, Animal{Id: 1, Name: "Cow", Type: "herd"})
+	m.PutInt(2, Animal{Id: 2, Name: "Sheep", Type: "Herding"})
+	m.PutInt(3, Animal{Id: 3, Name: "Cat", Type: "pet"})
+
+	assert.ElementsMatch(t, []string{"HERD", "HERDING"}, m.GetByIndexValuesContaining("Type", "erd"))
+	assert.Equal(t, 2, len(m.GetByIndexContaining("Type", "ERD")))
+	assert.Equal(t, 3, len(m.GetByIndexContaining("Type", "")))
+	assert.Equal(t, 0, len(m.GetByIndexContaining("Type", "fish")))
+	assert.Equal(t, 0, len(m.GetByIndexValuesContaining("Color", "erd")))
+}
+
+func TestGetAllIndexed(t *testing.T) {
+	m := NewTaggedPersonMap()
+
+	m.PutInt(1, Person{Id: 1, Tags: []string{"admin", "dev"}})
+	m.PutInt(2, Person{Id: 2, Tags: []string{"dev"}})
+	m.PutInt(3, Person{Id: 3})
+	m.PutInt(4, Person{Id: 4, Tags: []string{""}})
+
+	assert.Equal(t, 2, len(m.GetAllIndexed("Tag")))
+	assert.Equal(t, 4, len(m.Values()))
+	assert.NotNil(t, m.GetAllIndexed("Color"))
+	assert.Equal(t, 0, len(m.GetAllIndexed("Color")))
+
+	m.RemoveInt(2)
+	list := m.GetAllIndexed("Tag")
+	assert.Equal(t, 1, len(list))
+	assert.Equal(t, 1, list[0].Id)
+}
+
+func TestGetUnindexed(t *testing.T) {
+	m := NewTaggedPersonMap()
+
+	m.PutInt(1, Person{Id: 1, LastName: "Smith", Tags: []string{"admin", "dev"}})
+	m.PutInt(2, Person{Id: 2, Tags: []string{"dev"}})
+	m.PutInt(3, Person{Id: 3, LastName: "Doe"})
+	m.PutInt(4, Person{Id: 4, Tags: []string{""}})
+
+	assert.Equal(t, 0, len(m.GetByIndex("Tag", "")))
+	assert.ElementsMatch(t, []int{3, 4}, personIds(m.GetUnindexed("Tag")))
+	assert.ElementsMatch(t, []int{2, 4}, personIds(m.GetUnindexed("LastName")))
+	assert.NotNil(t, m.GetUnindexed("Color"))
+	assert.Equal(t, 0, len(m.GetUnindexed("Color")))
+
+	// scan doesn't race with index changes
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for range 100 {
+			m.AddIndex("Id", func(p *Person) string { return strconv.Itoa(p.Id) })
+			m.RemoveIndex("Id")
+		}
+	}()
+	for range 100 {
+		assert.ElementsMatch(t, []int{3, 4}, personIds(m.GetUnindexed("Tag")))
+	}
+	wg.Wait()
+}
+
+func personIds(list []Person) []int {
+	ids := []int{}
+	for _, p := range list {
+		ids = append(ids, p.Id)
+	}
+	return ids
+}
+
+func TestCountByIndex(t *testing.T) {
+	m := NewAnimalMap()
+
+	m.PutInt(1, Animal{Id: 1, Name: "Cow", Type: "big"})
+	m.PutInt(2, Animal{Id: 2, Name: "Horse", Type: "big"})
+	m.PutInt(3, Animal{Id: 3, Name: "Cat", Type: "small"})
+
+	assert.Equal(t, 2, m.CountByIndex("Type", "Big"))
+	assert.Equal(t, 1, m.CountByIndex("Type", "small"))
+	assert.Equal(t, 0, m.CountByIndex("Type", "huge"))
+	assert.Equal(t, 2, len(m.GetIndexKeys("Type")))
+}
+
+func TestRemoveByIndex(t *testing.T) {
+	m := NewAnimalMap()
+
+	m.PutInt(1, Animal{Id: 1, Name: "Dodo", Type: "extinct", Role: "bird"})
+	m.PutInt(2, Animal{Id: 2, Name: "Mammoth", Type: "extinct", Role: "herbivore"})
+	m.PutInt(3, Animal{Id: 3, Name: "Cat", Type: "small", Role: "pet"})
+
+	assert.Equal(t, 2, m.RemoveByIndex("Type", "Extinct"))
+	assert.Equal(t, 1, m.Size())
+	assert.Equal(t, 0, len(m.GetByIndex("Type", "extinct")))
+	assert.Equal(t, 0, len(m.GetByIndex("Role", "bird")))
+	assert.Equal(t, 0, len(m.GetByIndex("Role", "herbivore")))
+	assert.Equal(t, 1, len(m.GetByIndex("Role", "pet")))
+
+	assert.Equal(t, 0, m.RemoveByIndex("Type", "extinct"))
+	assert.Equal(t, 0, m.RemoveByIndex("Type", "unknown"))
+}
+
+func TestRemoveIf(t *testing.T) {
+	m := NewAnimalMap()
+
+	for i := range 100 {
+		typ := "small"
+		if i%4 == 0 {
+			typ = "big"
+		}
+		m.PutInt(i, Animal{Id: i, Name: "animal" + strconv.Itoa(i), Type: typ})
+	}
+
+	removed := m.RemoveIf(func(a Animal) bool {
+		return a.Id%2 == 0
+	})
+	assert.Equal(t, 50, removed)
+	assert.Equal(t, 50, m.Size())
+	assert.Equal(t, 0, len(m.GetByIndex("Type", "big")))
+	assert.Equal(t, 50, len(m.GetByIndex("Type", "small")))
+	assert.False(t, m.ContainsKeyInt(10))
+
+	assert.Equal(t, 0, m.RemoveIf(func(a Animal) bool { return false }))
+}
+
+func TestRemoveAll(t *testing.T) {
+	for _, count := range []int{100, 30001} {
+		m := NewAnimalMap()
+
+		keys := []string{}
+		for i := range count {
+			m.PutInt(i, Animal{Id: i, Name: "animal" + strconv.Itoa(i), Type: "t" + strconv.Itoa(i%3)})
+			if i%3 == 0 {
+				keys = append(keys, strconv.Itoa(i))
+			}
+		}
+		keys = append(keys, "missing")
+
+		assert.Equal(t, len(keys)-1, m.RemoveAll(keys))
+		assert.Equal(t, count-len(keys)+1, m.Size())
+		assert.Equal(t, 0, len(m.GetByIndex("Type", "t0")))
+		assert.ElementsMatch(t, []string{"T1", "T2"}, m.GetIndexKeys("Type"))
+		assert.False(t, m.ContainsKeyInt(3))
+		assert.True(t, m.ContainsKeyInt(4))
+		assert.Equal(t, 0, m.RemoveAll(keys))
+	}
+}
+
+func TestRemoveAllMultiValue(t *testing.T) {
+	m := NewTaggedPersonMap()
+	m.PutInt(1, Person{Id: 1, LastName: "Smith", Tags: []string{"admin", "dev"}})
+	m.PutInt(2, Person{Id: 2, LastName: "Doe", Tags: []string{"dev"}})
+	m.PutInt(3, Person{Id: 3, LastName: "Brown", Tags: []string{"ops"}})
+
+	assert.Equal(t, 2, m.RemoveAll([]string{"1", "3"}))
+	assert.Equal(t, []string{"DEV"}, m.GetIndexKeys("Tag"))
+	assert.Equal(t, []string{"DOE"}, m.GetIndexKeys("LastName"))
+	assert.Equal(t, []int{2}, personIds(m.GetByIndex("Tag", "dev")))
+}
+
+func TestGetAndRemove(t *testing.T) {
+	m := NewAnimalMap()
+
+	count := 1000
+	for i := range count {
+		m.PutInt(i, Animal{Id: i, Name: "animal" + strconv.Itoa(i), Type: "job"})
+	}
+
+	var taken atomic.Int64
+	workers := 8
+	ch := make(chan int, workers)
+	for range workers {
+		go func() {
+			for i := range count {
+				if _, ok := m.GetAndRemove(strconv.Itoa(i)); ok {
+					taken.Add(1)
+				}
+			}
+			ch <- 1
+		}()
+	}
+	waitChan(ch, workers)
+
+	assert.Equal(t, int64(count), taken.Load())
+	assert.Equal(t, 0, m.Size())
+	assert.Equal(t, 0, len(m.GetByIndex("Type", "job")))
+}
+
+func TestCaseSensitive(t *testing.T) {
+	m := NewIndexedMapWithOptions(map[string]IndexFunc[Person]{
+		"SSN": func(r *Person) string {
+			return r.SSN
+		},
+	}, Options[Person]{CaseSensitive: true})
+
+	m.Put("a1", Person{Id: 1, SSN: "abc"})
+	m.Put("A1", Person{Id: 2, SSN: "ABC"})
+
+	assert.Equal(t, 2, m.Size())
+	a, ok := m.Get("a1")
+	assert.True(t, ok)
+	assert.Equal(t, 1, a.Id)
+	assert.Equal(t, 1, len(m.GetByIndex("SSN", "abc")))
+	assert.Equal(t, 0, len(m.GetByIndex("SSN", "Abc")))
+
+	_, ok = m.Remove("A1")
+	assert.True(t, ok)
+	assert.True(t, m.ContainsKey("a1"))
+	assert.Equal(t, 0, m.CountByIndex("SSN", "ABC"))
+}
+
+func TestKeyNormalizer(t *testing.T) {
+	m := NewIndexedMapWithOptions(map[string]IndexFunc[Person]{
+		"SSN": func(r *Person) string {
+			return r.SSN
+		},
+	}, Options[Person]{KeyNormalizer: strings.TrimSpace})
+
+	m.Put(" aGVsbG8= ", Person{Id: 1, SSN: " abc"})
+	m.Put("aGVsbG8=", Person{Id: 2, SSN: "abc "})
+	m.Put("AGVSBG8=", Person{Id: 3, SSN: "ABC"})
+	m.PutInt(7, Person{Id: 7, SSN: "xyz"})
+
+	assert.Equal(t, 3, m.Size())
+	a, ok := m.Get("aGVsbG8=  ")
+	assert.True(t, ok)
+	assert.Equal(t, 2, a.Id)
+	assert.True(t, m.ContainsKeyInt(7))
+	assert.True(t, m.ContainsKey(" 7"))
+	assert.Equal(t, 1, len(m.GetByIndex("SSN", "abc")))
+	assert.Equal(t, 1, len(m.GetByIndex("SSN", " ABC ")))
+
+	_, ok = m.Remove(" AGVSBG8=")
+	assert.True(t, ok)
+	assert.Equal(t, 0, m.CountByIndex("SSN", "ABC"))
+}
+
+func TestGetByIndexMissingValue(t *testing.T) {
+	m := NewAnimalMap()
+
+	for i := range 1000 {
+		assert.Equal(t, 0, len(m.GetByIndex("Type", "missing"+strconv.Itoa(i))))
+		assert.Equal(t, 0, m.CountByIndex("Type", "missing"+strconv.Itoa(i)))
+		assert.Equal(t, 0, m.GetByIndexUnderlyingMap("Type", "missing"+strconv.Itoa(i)).Size())
+	}
+	assert.Equal(t, 0, len(m.GetIndexKeys("Type")))
+
+	m.PutInt(1, Animal{Id: 1, Name: "Cat", Type: "small"})
+	m.GetByIndexAny("Type", "big", "huge")
+	assert.Equal(t, []string{"SMALL"}, m.GetIndexKeys("Type"))
+}
+
+func TestPutIfAbsent(t *testing.T) {
+	m := NewAnimalMap()
+
+	a, loaded := m.PutIfAbsent("1", Animal{Id: 1, Name: "Cat", Type: "small"})
+	assert.False(t, loaded)
+	assert.Equal(t, "Cat", a.Name)
+
+	a, loaded = m.PutIfAbsent("1", Animal{Id: 1, Name: "Cow", Type: "big"})
+	assert.True(t, loaded)
+	assert.Equal(t, "Cat", a.Name)
+	assert.Equal(t, 1, m.Size())
+	assert.Equal(t, 1, len(m.GetByIndex("Type", "small")))
+	assert.Equal(t, 0, len(m.GetByIndex("Type", "big")))
+
+	var added atomic.Int32
+	var wg sync.WaitGroup
+	for i := range 100 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, loaded := m.PutIfAbsent("2", Animal{Id: 2, Name: "animal" + strconv.Itoa(i), Type: strconv.Itoa(i)}); !loaded {
+				added.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, int32(1), added.Load())
+	assert.Equal(t, 2, m.Size())
+	assert.Equal(t, 2, len(m.GetIndexKeys("Type")))
+}
+
+func TestRename(t *testing.T) {
+	m := NewAnimalMap()
+
+	events := []ChangeEvent[Animal]{}
+	m.OnChange(func(e ChangeEvent[Animal]) {
+		events = append(events, e)
+	})
+	m.PutInt(1, Animal{Id: 1, Name: "Cat", Type: "small", Role: "predator"})
+	m.PutInt(2, Animal{Id: 2, Name: "Cow", Type: "big", Role: "prey"})
+	events = events[:0]
+
+	assert.True(t, m.Rename("1", "Kitty"))
+	assert.False(t, m.ContainsKey("1"))
+	a, ok := m.Get("kitty")
+	assert.True(t, ok)
+	assert.Equal(t, "Cat", a.Name)
+	assert.Equal(t, []string{"Kitty"}, m.GetKeysByIndex("Type", "small"))
+	assert.Equal(t, []string{"Kitty"}, m.GetKeysByIndex("RoleType", "predator:small"))
+	assert.Equal(t, map[string]Animal{"Kitty": a}, m.GetByIndexWithKeys("Role", "predator"))
+	assert.Equal(t, 2, m.Size())
+	assert.Empty(t, m.Validate())
+	assert.Equal(t, []ChangeEvent[Animal]{
+		{Op: OpDelete, Key: "1", Old: a},
+		{Op: OpInsert, Key: "KITTY", New: a},
+	}, events)
+
+	assert.False(t, m.Rename("1", "3"))
+	assert.False(t, m.Rename("kitty", "2"))
+	assert.False(t, m.Rename("kitty", "KITTY"))
+	assert.True(t, m.ContainsKey("kitty"))
+	assert.True(t, m.ContainsKeyInt(2))
+	assert.Equal(t, 2, len(events))
+}
+
+func TestRenameUnique(t *testing.T) {
+	m := NewUniquePersonMap()
+
+	assert.NoError(t, m.PutErr("1", Person{Id: 1, SSN: "111"}))
+	assert.True(t, m.Rename("1", "2"))
+	assert.ErrorIs(t, m.PutErr("3", Person{Id: 3, SSN: "111"}), ErrUniqueViolation)
+	assert.NoError(t, m.PutErr("2", Person{Id: 2, SSN: "111", LastName: "John"}))
+	assert.Equal(t, 1, len(m.GetByIndex("SSN", "111")))
+}
+
+func TestSwap(t *testing.T) {
+	m := NewAnimalMap()
+
+	_, loaded := m.Swap("1", Animal{Id: 1, Name: "Cat", Type: "small"})
+	assert.False(t, loaded)
+
+	old, loaded := m.Swap("1", Animal{Id: 1, Name: "Lion", Type: "big"})
+	assert.True(t, loaded)
+	assert.Equal(t, "Cat", old.Name)
+	assert.Equal(t, 0, len(m.GetByIndex("Type", "small")))
+	assert.Equal(t, 1, len(m.GetByIndex("Type", "big")))
+	assert.Equal(t, 1, m.Size())
+}
+
+func TestPutReturning(t *testing.T) {
+	m := NewAnimalMap()
+
+	_, existed := m.PutReturning("cat", Animal{Id: 1, Name: "Cat", Type: "small"})
+	assert.False(t, existed)
+	old, existed := m.PutReturning("CAT", Animal{Id: 1, Name: "Lion", Type: "big"})
+	assert.True(t, existed)
+	assert.Equal(t, "Cat", old.Name)
+
+	_, existed = m.PutIntReturning(2, Animal{Id: 2, Name: "Dog", Type: "small"})
+	assert.False(t, existed)
+	old, existed = m.PutIntReturning(2, Animal{Id: 2, Name: "Wolf", Type: "big"})
+	assert.True(t, existed)
+	assert.Equal(t, "Dog", old.Name)
+
+	assert.Equal(t, 0, len(m.GetByIndex("Type", "small")))
+	assert.Equal(t, 2, len(m.GetByIndex("Type", "big")))
+}
+
+func TestCompareAndSwap(t *testing.T) {
+	m := NewAnimalMap()
+	eq := func(a, b Animal) bool {
+		return a.Name == b.Name && a.Type == b.Type
+	}
+
+	cat := Animal{Id: 1, Name: "Cat", Type: "small"}
+	lion := Animal{Id: 1, Name: "Lion", Type: "big"}
+	assert.False(t, m.CompareAndSwap("1", cat, lion, eq))
+
+	m.Put("1", cat)
+	assert.False(t, m.CompareAndSwap("1", lion, cat, eq))
+	assert.True(t, m.CompareAndSwap("1", cat, lion, eq))
+	assert.Equal(t, 0, len(m.GetByIndex("Type", "small")))
+	assert.Equal(t, 1, len(m.GetByIndex("Type", "big")))
+
+	var swapped atomic.Int64
+	ch := make(chan int, 8)
+	for i := range 8 {
+		go func() {
+			if m.CompareAndSwap("1", lion, Animal{Id: 1, Name: "Lion" + strconv.Itoa(i), Type: "big"}, eq) {
+				swapped.Add(1)
+			}
+			ch <- 1
+		}()
+	}
+	waitChan(ch, 8)
+	assert.Equal(t, int64(1), swapped.Load())
+}
+
+func TestComputeIfAbsent(t *testing.T) {
+	m := NewAnimalMap()
+
+	var calls atomic.Int32
+	factory := func() Animal {
+		calls.Add(1)
+		return Animal{Id: 1, Name: "Cat", Type: "small"}
+	}
+
+	var wg sync.WaitGroup
+	for range 100 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			a, _ := m.ComputeIfAbsent("1", factory)
+			assert.Equal(t, "Cat", a.Name)
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, int32(1), calls.Load())
+	assert.Equal(t, 1, m.Size())
+	assert.Equal(t, 1, len(m.GetByIndex("Type", "small")))
+
+	a, created := m.ComputeIfAbsent("2", func() Animal { return Animal{Id: 2, Name: "Cow", Type: "big"} })
+	assert.True(t, created)
+	assert.Equal(t, "Cow", a.Name)
+
+	_, created = m.ComputeIfAbsent("2", factory)
+	assert.False(t, created)
+	assert.Equal(t, int32(1), calls.Load())
+}
+
+func TestUpdate(t *testing.T) {
+	m := NewAnimalMap()
+
+	m.PutInt(1, Animal{Id: 1, Name: "Rabbit", Type: "small"})
+
+	ok := m.Update("1", func(a *Animal) bool {
+		a.Type = "big"
+		return true
+	})
+	assert.True(t, ok)
+
+	a, _ := m.GetInt(1)
+	assert.Equal(t, "big", a.Type)
+	assert.Equal(t, 0, len(m.GetByIndex("Type", "small")))
+	assert.Equal(t, 1, len(m.GetByIndex("Type", "big")))
+
+	ok = m.Update("1", func(a *Animal) bool {
+		a.Type = "huge"
+		return false
+	})
+	assert.False(t, ok)
+	a, _ = m.GetInt(1)
+	assert.Equal(t, "big", a.Type)
+	assert.Equal(t, 0, len(m.GetByIndex("Type", "huge")))
+
+	ok = m.Update("2", func(a *Animal) bool {
+		return true
+	})
+	assert.False(t, ok)
+	assert.False(t, m.ContainsKey("2"))
+	assert.Equal(t, 1, m.Size())
+}
+
+func TestForEach(t *testing.T) {
+	m := NewAnimalMap()
+
+	for i := range 10 {
+		m.PutInt(i, Animal{Id: i, Name: "animal" + strconv.Itoa(i)})
+	}
+
+	count := 0
+	m.ForEach(func(key string, a Animal) bool {
+		assert.Equal(t, strconv.Itoa(a.Id), key)
+		a.Name = "changed"
+		count++
+		return true
+	})
+	assert.Equal(t, 10, count)
+
+	a, _ := m.GetInt(3)
+	assert.Equal(t, "animal3", a.Name)
+
+	count = 0
+	m.ForEach(func(key string, a Animal) bool {
+		count++
+		return count < 3
+	})
+	assert.Equal(t, 3, count)
+}
+
+func TestFilter(t *testing.T) {
+	m := NewAnimalMap()
+
+	for i := range 100 {
+		m.PutInt(i, Animal{Id: i, Name: "animal" + strconv.Itoa(i), Type: "small"})
+	}
+
+	list := m.Filter(func(a Animal) bool {
+		return a.Id >= 90
+	})
+	assert.Equal(t, 10, len(list))
+	for _, a := range list {
+		assert.GreaterOrEqual(t, a.Id, 90)
+	}
+
+	list = m.Filter(func(a Animal) bool { return false })
+	assert.NotNil(t, list)
+	assert.Equal(t, 0, len(list))
+}
+
+func TestEntries(t *testing.T) {
+	m := NewAnimalMap()
+	assert.Equal(t, 0, len(m.Entries()))
+
+	for i := range 100 {
+		m.PutInt(i, Animal{Id: i, Name: "animal" + strconv.Itoa(i), Type: "small"})
+	}
+
+	entries := m.Entries()
+	assert.Equal(t, 100, len(entries))
+	for _, e := range entries {
+		assert.Equal(t, strconv.Itoa(e.Value.Id), e.Key)
+	}
+
+	entries[0].Value.Name = "changed"
+	o, _ := m.Get(entries[0].Key)
+	assert.NotEqual(t, "changed", o.Name)
+}
+
+func TestForEachSnapshot(t *testing.T) {
+	m := NewAnimalMap()
+	for i := range 10 {
+		m.PutInt(i, Animal{Id: i, Name: "animal" + strconv.Itoa(i)})
+	}
+	assert.ElementsMatch(t, m.Keys(), m.KeysSnapshot())
+
+	visited := []string{}
+	m.ForEachSnapshot(func(key string, value Animal) {
+		visited = append(visited, key)
+		assert.Equal(t, strconv.Itoa(value.Id), key)
+		m.PutInt(100, Animal{Id: 100})
+		for i := range 10 {
+			if strconv.Itoa(i) != key {
+				m.RemoveInt(i)
+			}
+		}
+	})
+	assert.Equal(t, 1, len(visited))
+	assert.Equal(t, 2, m.Size())
+}
+
+func TestCopyInto(t *testing.T) {
+	m := NewAnimalMap()
+	m.Put("cat", Animal{Id: 1, Name: "Cat"})
+	m.Put("dog", Animal{Id: 2, Name: "Dog"})
+
+	dst := map[string]Animal{
+		"DOG":   {Id: 20, Name: "Old dog"},
+		"HORSE": {Id: 3, Name: "Horse"},
+	}
+	m.CopyInto(dst)
+	assert.Equal(t, 3, len(dst))
+	assert.Equal(t, "Cat", dst["CAT"].Name)
+	assert.Equal(t, "Dog", dst["DOG"].Name)
+	assert.Equal(t, "Horse", dst["HORSE"].Name)
+}
+
+func TestGroupBy(t *testing.T) {
+	m := NewAnimalMap()
+
+	for i := range 30 {
+		m.PutInt(i, Animal{Id: i, Name: "animal" + strconv.Itoa(i%3), Type: "small"})
+	}
+	m.PutInt(100, Animal{Id: 100, Type: "big"})
+
+	groups := m.GroupBy(func(a Animal) string {
+		return a.Name
+	})
+	assert.Equal(t, 4, len(groups))
+	assert.Equal(t, 10, len(groups["animal0"]))
+	assert.Equal(t, 10, len(groups["animal2"]))
+	assert.Equal(t, 1, len(groups[""]))
+
+	assert.Equal(t, 0, len(NewAnimalMap().GroupBy(func(a Animal) string { return a.Name })))
+}
+
+func TestSnapshot(t *testing.T) {
+	m := NewAnimalMap()
+
+	m.PutInt(1, Animal{Id: 1, Name: "Cat", Type: "small"})
+	m.PutInt(2, Animal{Id: 2, Name: "Cow", Type: "big"})
+
+	s := m.Snapshot()
+
+	m.PutInt(3, Animal{Id: 3, Name: "Dog", Type: "small"})
+	m.PutInt(2, Animal{Id: 2, Name: "Cow", Type: "huge"})
+	m.RemoveInt(1)
+
+	assert.Equal(t, 2, s.Size())
+	assert.Equal(t, 1, len(s.GetByIndex("Type", "small")))
+	assert.Equal(t, 1, len(s.GetByIndex("Type", "big")))
+	assert.Equal(t, 0, len(s.GetByIndex("Type", "huge")))
+
+	s.PutInt(4, Animal{Id: 4, Name: "Pig", Type: "small"})
+	assert.False(t, m.ContainsKeyInt(4))
+	assert.Equal(t, 1, len(m.GetByIndex("Type", "small")))
+}
+
+func TestClone(t *testing.T) {
+	m := NewAnimalMap()
+
+	m.PutInt(1, Animal{Id: 1, Name: "Cat", Type: "small"})
+	m.PutInt(2, Animal{Id: 2, Name: "Cow", Type: "big"})
+	m.PutWithTTL("3", Animal{Id: 3, Name: "Dog", Type: "small"}, time.Hour)
+
+	c := m.Clone()
+
+	c.PutInt(2, Animal{Id: 2, Name: "Cow", Type: "huge"})
+	c.RemoveInt(1)
+	m.PutInt(4, Animal{Id: 4, Name: "Pig", Type: "small"})
+
+	assert.Equal(t, 2, c.Size())
+	assert.Equal(t, 1, len(c.GetByIndex("Type", "small")))
+	assert.Equal(t, 1, len(c.GetByIndex("Type", "huge")))
+	assert.False(t, c.ContainsKeyInt(4))
+
+	assert.Equal(t, 4, m.Size())
+	assert.Equal(t, 3, len(m.GetByIndex("Type", "small")))
+	assert.Equal(t, 1, len(m.GetByIndex("Type", "big")))
+
+	m.PutWithTTL("5", Animal{Id: 5, Name: "Fly", Type: "tiny"}, time.Nanosecond)
+	time.Sleep(time.Millisecond)
+	c = m.Clone()
+	_, ok := c.Get("5")
+	assert.False(t, ok)
+	assert.Equal(t, 1, c.RemoveExpired())
+}
+
+func TestEqual(t *testing.T) {
+	eq := func(a, b Animal) bool { return a == b }
+	m := NewAnimalMap()
+	assert.True(t, m.Equal(NewAnimalMap(), eq))
+
+	m.Put("cat", Animal{Id: 1, Name: "Cat", Type: "small"})
+	m.Put("cow", Animal{Id: 2, Name: "Cow", Type: "big"})
+
+	c := m.Clone()
+	assert.True(t, m.Equal(c, eq))
+	assert.True(t, c.Equal(m, eq))
+
+	c.Put("COW", Animal{Id: 2, Name: "Cow", Type: "huge"})
+	assert.False(t, m.Equal(c, eq))
+	assert.True(t, m.Equal(c, func(a, b Animal) bool { return a.Id == b.Id }))
+
+	c.Put("dog", Animal{Id: 3, Name: "Dog"})
+	assert.False(t, m.Equal(c, func(a, b Animal) bool { return a.Id == b.Id }))
+	assert.False(t, c.Equal(m, func(a, b Animal) bool { return a.Id == b.Id }))
+
+	other := NewIndexedMapWithOptions(map[string]IndexFunc[Animal]{}, Options[Animal]{CaseSensitive: true})
+	other.Put("cat", Animal{Id: 1, Name: "Cat", Type: "small"})
+	other.Put("cow", Animal{Id: 2, Name: "Cow", Type: "big"})
+	assert.True(t, m.Equal(other, eq))
+
+	// expired elements are absent on both sides
+	other.PutWithTTL("dog", Animal{Id: 3, Name: "Dog"}, time.Millisecond)
+	m.PutWithTTL("pig", Animal{Id: 4, Name: "Pig"}, time.Millisecond)
+	time.Sleep(5 * time.Millisecond)
+	assert.True(t, m.Equal(other, eq))
+	assert.True(t, other.Equal(m, eq))
+}
+
+func TestMerge(t *testing.T) {
+	m := NewAnimalMap()
+	m.PutInt(1, Animal{Id: 1, Name: "Cat", Type: "small"})
+	m.PutInt(2, Animal{Id: 2, Name: "Cow", Type: "big"})
+
+	other := NewIndexedMap(map[string]IndexFunc[Animal]{
+		"Name": func(a *Animal) string {
+			return a.Name
+		},
+	})
+	other.PutInt(2, Animal{Id: 2, Name: "Bull", Type: "huge"})
+	other.PutInt(3, Animal{Id: 3, Name: "Dog", Type: "small"})
+
+	m.Merge(other)
+
+	assert.Equal(t, 3, m.Size())
+	a, _ := m.GetInt(2)
+	assert.Equal(t, "Bull", a.Name)
+	assert.Equal(t, 0, len(m.GetByIndex("Type", "big")))
+	assert.Equal(t, 1, len(m.GetByIndex("Type", "huge")))
+	assert.Equal(t, 2, len(m.GetByIndex("Type", "small")))
+	assert.Equal(t, 2, other.Size())
+
+	other.PutInt(2, Animal{Id: 2, Name: "Ox", Type: "big"})
+	m.MergeFunc(other, func(existing, incoming Animal) Animal {
+		return existing
+	})
+	a, _ = m.GetInt(2)
+	assert.Equal(t, "Bull", a.Name)
+	assert.Equal(t, 0, len(m.GetByIndex("Type", "big")))
+}
+
+func TestPutAllUneven(t *testing.T) {
+	for _, count := range []int{9999, 10000, 10007, 1000003} {
+		m := NewAnimalMap()
+
+		data := make([]Animal, 0, count)
+		for i := range count {
+			data = append(data, Animal{Id: i, Name: "animal-" + strconv.Itoa(i), NumType: i % 3})
+		}
+		m.PutAll(data, func(a *Animal) string { return strconv.Itoa(a.Id) })
+
+		assert.Equal(t, count, m.Size())
+		assert.Equal(t, count, len(m.Keys()))
+		assert.True(t, m.ContainsKeyInt(count-1))
+		assert.Equal(t, count, m.CountByIndex("NumType", "0")+m.CountByIndex("NumType", "1")+m.CountByIndex("NumType", "2"))
+	}
+}
+
+func TestPutAllWith(t *testing.T) {
+	for _, parallelism := range []int{1, 3, 64, 1 << 20} {
+		m := NewAnimalMap()
+
+		count := 20011
+		data := make([]Animal, 0, count)
+		for i := range count {
+			data = append(data, Animal{Id: i, NumType: i % 3})
+		}
+		m.PutAllWith(data, func(a *Animal) string { return strconv.Itoa(a.Id) }, parallelism)
+
+		assert.Equal(t, count, m.Size())
+		assert.Equal(t, count, m.CountByIndex("NumType", "0")+m.CountByIndex("NumType", "1")+m.CountByIndex("NumType", "2"))
+		assert.Equal(t, m.CountByIndex("NumType", "1"), len(m.GetByIndexParallelWith("NumType", "1", parallelism)))
+
+		keys := make([]string, 0, count)
+		for i := range count {
+			keys = append(keys, strconv.Itoa(i))
+		}
+		assert.Equal(t, count, m.RemoveAllWith(keys, parallelism))
+		assert.Equal(t, 0, m.Size())
+
+		entries := map[string]Animal{}
+		for _, a := range data {
+			entries[strconv.Itoa(a.Id)] = a
+		}
+		m.PutAllMapWith(entries, parallelism)
+		assert.Equal(t, count, m.Size())
+
+		result := m.GetByIndexBatchWith([]IndexQuery{{"NumType", "0"}, {"NumType", "2"}, {"Type", "none"}}, parallelism)
+		assert.Equal(t, m.CountByIndex("NumType", "0"), len(result[0]))
+		assert.Equal(t, m.CountByIndex("NumType", "2"), len(result[1]))
+		assert.Empty(t, result[2])
+	}
+
+	m := NewAnimalMap()
+	for _, parallelism := range []int{-1, 0} {
+		assert.PanicsWithValue(t, ErrInvalidParallelism, func() { m.PutAllWith(nil, nil, parallelism) })
+		assert.PanicsWithValue(t, ErrInvalidParallelism, func() { m.PutAllMapWith(nil, parallelism) })
+		assert.PanicsWithValue(t, ErrInvalidParallelism, func() { m.RemoveAllWith(nil, parallelism) })
+		assert.PanicsWithValue(t, ErrInvalidParallelism, func() { m.GetByIndexParallelWith("Type", "none", parallelism) })
+		assert.PanicsWithValue(t, ErrInvalidParallelism, func() { m.GetByIndexBatchWith(nil, parallelism) })
+	}
+	m.PutInt(1, Animal{Id: 1, Type: "small"})
+	assert.Equal(t, 1, m.Size())
+}
+
+func TestNewIndexedMapSized(t *testing.T) {
+	m := NewIndexedMapSized(map[string]IndexFunc[Animal]{
+		"Type": func(a *Animal) string {
+			return a.Type
+		},
+	}, 20000)
+
+	data := make([]Animal, 0, 20000)
+	for i := range cap(data) {
+		data = append(data, Animal{Id: i, Type: "t" + strconv.Itoa(i%4)})
+	}
+	m.PutAll(data, func(a *Animal) string { return strconv.Itoa(a.Id) })
+
+	assert.Equal(t, len(data), m.Size())
+	assert.Equal(t, 5000, len(m.GetByIndex("Type", "T1")))
+	assert.True(t, NewIndexedMapSized(map[string]IndexFunc[Animal]{}, 0).IsEmpty())
+}
+
+func TestAddIndex(t *testing.T) {
+	m := NewAnimalMap()
+
+	for i := range 1000 {
+		m.PutInt(i, Animal{Id: i, Name: "animal" + strconv.Itoa(i%10)})
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := range 2000 {
+			m.PutInt(i, Animal{Id: i, Name: "new" + strconv.Itoa(i%10)})
+		}
+	}()
+
+	ok := m.AddIndex("Name", func(a *Animal) string {
+		return a.Name
+	})
+	assert.True(t, ok)
+	wg.Wait()
+
+	for i := range 10 {
+		assert.Equal(t, 0, len(m.GetByIndex("Name", "animal"+strconv.Itoa(i))))
+		assert.Equal(t, 200, len(m.GetByIndex("Name", "new"+strconv.Itoa(i))))
+	}
+
+	assert.False(t, m.AddIndex("Type", func(a *Animal) string {
+		return a.Name
+	}))
+}
+
+func TestRemoveIndex(t *testing.T) {
+	m := NewAnimalMap()
+
+	m.PutInt(1, Animal{Id: 1, Name: "Cat", Type: "small", Role: "pet"})
+
+	assert.True(t, m.RemoveIndex("Type"))
+	assert.False(t, m.RemoveIndex("Type"))
+
+	m.PutInt(2, Animal{Id: 2, Name: "Dog", Type: "small", Role: "pet"})
+	m.RemoveInt(1)
+
+	assert.Equal(t, 0, len(m.GetByIndex("Type", "small")))
+	assert.Equal(t, 0, len(m.GetIndexKeys("Type")))
+	assert.Equal(t, 0, m.CountByIndex("Type", "small"))
+	assert.Equal(t, 1, len(m.GetByIndex("Role", "pet")))
+
+	assert.True(t, m.AddIndex("Type", func(a *Animal) string {
+		return a.Type
+	}))
+	assert.Equal(t, 1, len(m.GetByIndex("Type", "small")))
+}
+
+func TestIndexNames(t *testing.T) {
+	m := NewAnimalMap()
+
+	assert.Equal(t, []string{"NumType", "Role", "RoleType", "Type"}, m.IndexNames())
+	assert.True(t, m.HasIndex("Type"))
+	assert.False(t, m.HasIndex("type"))
+
+	m.RemoveIndex("Role")
+	m.AddIndex("Name", func(a *Animal) string {
+		return a.Name
+	})
+	assert.Equal(t, []string{"Name", "NumType", "RoleType", "Type"}, m.IndexNames())
+	assert.False(t, m.HasIndex("Role"))
+	assert.True(t, m.HasIndex("Name"))
+}
+
+func TestReindex(t *testing.T) {
+	size := "small"
+	m := NewIndexedMap(map[string]IndexFunc[Animal]{
+		"Size": func(a *Animal) string {
+			return size
+		},
+	})
+
+	for i := range 10 {
+		m.PutInt(i, Animal{Id: i, Name: "animal" + strconv.Itoa(i)})
+	}
+	assert.Equal(t, 10, len(m.GetByIndex("Size", "small")))
+
+	size = "big"
+	m.Reindex()
+	assert.Equal(t, 0, len(m.GetByIndex("Size", "small")))
+	assert.Equal(t, 10, len(m.GetByIndex("Size", "big")))
+	assert.Equal(t, []string{"BIG"}, m.GetIndexKeys("Size"))
+	assert.Equal(t, 10, m.Size())
+}
+
+func TestSetIndexFunc(t *testing.T) {
+	m := NewAnimalMap()
+
+	m.PutInt(1, Animal{Id: 1, Name: "Cow", Type: "big", Role: "food"})
+	m.PutInt(2, Animal{Id: 2, Name: "Cat", Type: "small", Role: "pet"})
+	m.PutInt(3, Animal{Id: 3, Name: "Horse", Type: "big", Role: "pet"})
+
+	assert.NoError(t, m.SetIndexFunc("Type", func(a *Animal) string {
+		return a.Name[:1]
+	}))
+	assert.Equal(t, 0, len(m.GetByIndex("Type", "big")))
+	assert.Equal(t, 2, len(m.GetByIndex("Type", "c")))
+	assert.Equal(t, 1, len(m.GetByIndex("Type", "h")))
+	assert.Equal(t, 2, len(m.GetByIndex("Role", "pet")))
+
+	m.PutInt(4, Animal{Id: 4, Name: "Hen", Type: "small"})
+	assert.Equal(t, 2, len(m.GetByIndex("Type", "h")))
+
+	assert.ErrorIs(t, m.SetIndexFunc("Color", func(a *Animal) string { return "" }), ErrIndexNotFound)
+	assert.False(t, m.HasIndex("Color"))
+	assert.Nil(t, m.Validate())
+}
+
+func TestSetIndexFuncUnique(t *testing.T) {
+	m := NewUniquePersonMap()
+	m.PutInt(1, Person{Id: 1, LastName: "Smith", SSN: "111-22"})
+	m.PutInt(2, Person{Id: 2, LastName: "Doe", SSN: "111-33"})
+	m.PutWithTTL("3", Person{Id: 3, LastName: "Brown", SSN: "222-33"}, time.Millisecond)
+	time.Sleep(5 * time.Millisecond)
+
+	// both elements would get 111, old function is kept
+	err := m.SetIndexFunc("SSN", func(p *Person) string { return p.SSN[:3] })
+	assert.ErrorIs(t, err, ErrUniqueViolation)
+	assert.Equal(t, []int{1}, personIds(m.GetByIndex("SSN", "111-22")))
+	assert.Empty(t, m.GetByIndex("SSN", "111"))
+	assert.NoError(t, m.PutErr("1", Person{Id: 1, LastName: "Smith", SSN: "111-22"}))
+
+	// expired element doesn't hold its value
+	assert.NoError(t, m.SetIndexFunc("SSN", func(p *Person) string { return p.SSN[4:] }))
+	assert.Equal(t, []int{2}, personIds(m.GetByIndex("SSN", "33")))
+	assert.ErrorIs(t, m.PutErr("4", Person{Id: 4, SSN: "999-22"}), ErrUniqueViolation)
+	assert.Nil(t, m.Validate())
+}
+
+func TestUnknownIndex(t *testing.T) {
+	m := NewAnimalMap()
+
+	m.PutInt(1, Animal{Id: 1, Name: "Cat", Type: "small"})
+
+	assert.Equal(t, 0, len(m.GetByIndex("Nonexistent", "x")))
+	assert.Equal(t, 0, len(m.GetByIndexAny("Nonexistent", "x", "y")))
+	assert.Equal(t, 0, len(m.GetIndexKeys("Nonexistent")))
+	assert.Equal(t, 0, m.CountByIndex("Nonexistent", "x"))
+	assert.Equal(t, 0, m.RemoveByIndex("Nonexistent", "x"))
+	assert.Equal(t, 0, m.GetByIndexUnderlyingMap("Nonexistent", "x").Size())
+
+	_, err := m.GetByIndexErr("Nonexistent", "x")
+	assert.ErrorIs(t, err, ErrIndexNotFound)
+	assert.Contains(t, err.Error(), "Nonexistent")
+
+	list, err := m.GetByIndexErr("Type", "small")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(list))
+}
+
+func TestGetByIndexOrDefault(t *testing.T) {
+	m := NewAnimalMap()
+	def := []Animal{{Name: "Nobody"}}
+
+	m.PutInt(1, Animal{Id: 1, Name: "Cat", Type: "small"})
+
+	assert.Equal(t, "Cat", m.GetByIndexOrDefault("Type", "SMALL", def)[0].Name)
+	assert.Equal(t, def, m.GetByIndexOrDefault("Type", "big", def))
+	assert.Equal(t, def, m.GetByIndexOrDefault("Color", "red", def))
+	assert.Nil(t, m.GetByIndexOrDefault("Color", "red", nil))
+}
+
+func TestKeyNotFound(t *testing.T) {
+	m := NewAnimalMap()
+
+	m.Put("cat", Animal{Id: 1, Name: "Cat", Type: "small"})
+
+	o, err := m.GetErr("CAT")
+	assert.NoError(t, err)
+	assert.Equal(t, "Cat", o.Name)
+
+	_, err = m.GetErr("dog")
+	assert.ErrorIs(t, err, ErrKeyNotFound)
+	assert.Contains(t, err.Error(), "dog")
+
+	_, err = m.RemoveErr("dog")
+	assert.ErrorIs(t, err, ErrKeyNotFound)
+
+	o, err = m.RemoveErr("cat")
+	assert.NoError(t, err)
+	assert.Equal(t, "Cat", o.Name)
+	assert.True(t, m.IsEmpty())
+
+	m.Close()
+	_, err = m.RemoveErr("cat")
+	assert.ErrorIs(t, err, ErrClosed)
+}
+
+func TestGetOneByIndex(t *testing.T) {
+	persons := NewPersonMap()
+
+	persons.PutInt(1, Person{Id: 1, FirstName: "Alex", SSN: "123123123"})
+	persons.PutInt(2, Person{Id: 2, FirstName: "John", SSN: "345343123"})
+
+	p, ok := persons.GetOneByIndex("SSN", "345343123")
+	assert.True(t, ok)
+	assert.Equal(t, "John", p.FirstName)
+
+	_, ok = persons.GetOneByIndex("SSN4", "3123")
+	assert.True(t, ok)
+
+	_, ok = persons.GetOneByIndex("SSN", "000000000")
+	assert.False(t, ok)
+	assert.Equal(t, 2, len(persons.GetIndexKeys("SSN")))
+}
+
+func TestGetByIndexFirstN(t *testing.T) {
+	m := NewAnimalMap()
+
+	for i := range 100 {
+		m.PutInt(i, Animal{Id: i, Type: "t" + strconv.Itoa(i%2)})
+	}
+
+	first := m.GetByIndexFirstN("Type", "T1", 10)
+	assert.Equal(t, 10, len(first))
+	for _, a := range first {
+		assert.Equal(t, "t1", a.Type)
+	}
+	assert.Subset(t, m.GetByIndex("Type", "t1"), first)
+	assert.Equal(t, 50, len(m.GetByIndexFirstN("Type", "t0", 1000)))
+	assert.Equal(t, []Animal{}, m.GetByIndexFirstN("Type", "t0", 0))
+	assert.Equal(t, []Animal{}, m.GetByIndexFirstN("Type", "t0", -1))
+	assert.Equal(t, []Animal{}, m.GetByIndexFirstN("Type", "t2", 10))
+	assert.Equal(t, []Animal{}, m.GetByIndexFirstN("Color", "red", 10))
+}
+
+func TestGetByKeyOrIndex(t *testing.T) {
+	persons := NewPersonMap()
+
+	persons.PutInt(1, Person{Id: 1, FirstName: "Alex", SSN: "123123123"})
+	persons.PutInt(2, Person{Id: 2, FirstName: "John", SSN: "345343123"})
+
+	p, ok := persons.GetByKeyOrIndex("1", "SSN", "345343123")
+	assert.True(t, ok)
+	assert.Equal(t, 1, p.Id)
+
+	p, ok = persons.GetByKeyOrIndex("3", "SSN", "345343123")
+	assert.True(t, ok)
+	assert.Equal(t, 2, p.Id)
+
+	_, ok = persons.GetByKeyOrIndex("3", "SSN", "000000000")
+	assert.False(t, ok)
+	_, ok = persons.GetByKeyOrIndex("3", "Nonexistent", "345343123")
+	assert.False(t, ok)
+}
+
+func TestContainsIndexValue(t *testing.T) {
+	m := NewAnimalMap()
+
+	m.PutInt(1, Animal{Id: 1, Name: "Cat", Type: "small"})
+
+	assert.True(t, m.ContainsIndexValue("Type", "Small"))
+	assert.False(t, m.ContainsIndexValue("Type", "big"))
+	assert.False(t, m.ContainsIndexValue("Nonexistent", "small"))
+
+	m.RemoveInt(1)
+	assert.False(t, m.ContainsIndexValue("Type", "small"))
+}
+
+func TestGetByIndexes(t *testing.T) {
+	m := NewAnimalMap()
+
+	m.PutInt(1, Animal{Id: 1, Name: "Wolf", Type: "big", Role: "predator"})
+	m.PutInt(2, Animal{Id: 2, Name: "Bear", Type: "big", Role: "predator"})
+	m.PutInt(3, Animal{Id: 3, Name: "Cow", Type: "big", Role: "prey"})
+	m.PutInt(4, Animal{Id: 4, Name: "Cat", Type: "small", Role: "predator"})
+
+	assert.Equal(t, 2, len(m.GetByIndexes(map[string]string{"Type": "Big", "Role": "predator"})))
+	assert.Equal(t, 1, len(m.GetByIndexes(map[string]string{"Type": "small", "Role": "PREDATOR"})))
+	assert.Equal(t, 3, len(m.GetByIndexes(map[string]string{"Type": "big"})))
+	assert.Equal(t, 0, len(m.GetByIndexes(map[string]string{"Type": "small", "Role": "prey"})))
+	assert.Equal(t, 0, len(m.GetByIndexes(map[string]string{"Type": "big", "Role": "unknown"})))
+	assert.Equal(t, 0, len(m.GetByIndexes(map[string]string{"Type": "big", "Nonexistent": "x"})))
+	assert.NotNil(t, m.GetByIndexes(nil))
+}
+
+func TestIntersectDifferenceByIndex(t *testing.T) {
+	m := NewAnimalMap()
+
+	m.PutInt(1, Animal{Id: 1, Name: "Wolf", Type: "big", Role: "predator"})
+	m.PutInt(2, Animal{Id: 2, Name: "Bear", Type: "big", Role: "predator"})
+	m.PutInt(3, Animal{Id: 3, Name: "Cow", Type: "big", Role: "prey"})
+	m.PutInt(4, Animal{Id: 4, Name: "Mouse", Type: "small", Role: "prey"})
+
+	assert.ElementsMatch(t, []int{1, 2}, animalIds(m.DifferenceByIndex("Type", "big", "Role", "prey")))
+	assert.ElementsMatch(t, []int{3}, animalIds(m.IntersectByIndex("Type", "Big", "Role", "PREY")))
+	assert.ElementsMatch(t, []int{1, 2, 3}, animalIds(m.DifferenceByIndex("Type", "big", "Role", "unknown")))
+	assert.ElementsMatch(t, []int{1, 2, 3}, animalIds(m.DifferenceByIndex("Type", "big", "Color", "red")))
+	assert.Empty(t, m.IntersectByIndex("Type", "big", "Role", "unknown"))
+	assert.Empty(t, m.DifferenceByIndex("Type", "huge", "Role", "prey"))
+	assert.NotNil(t, m.IntersectByIndex("Color", "red", "Role", "prey"))
+
+	p := NewTaggedPersonMap()
+	p.PutInt(1, Person{Id: 1, Tags: []string{"a", "b"}})
+	p.PutInt(2, Person{Id: 2, Tags: []string{"a"}})
+	p.PutInt(3, Person{Id: 3, Tags: []string{"b", "c"}})
+
+	assert.ElementsMatch(t, []int{1}, personIds(p.IntersectByIndex("Tag", "a", "Tag", "b")))
+	assert.ElementsMatch(t, []int{2}, personIds(p.DifferenceByIndex("Tag", "a", "Tag", "b")))
+	assert.ElementsMatch(t, []int{1, 2}, personIds(p.IntersectByIndex("Tag", "a", "Tag", "a")))
+	assert.Empty(t, p.DifferenceByIndex("Tag", "a", "Tag", "a"))
+}
+
+func TestGetByIndexBatch(t *testing.T) {
+	m := NewAnimalMap()
+
+	for i := range 30 {
+		m.PutInt(i, Animal{Id: i, Name: "animal" + strconv.Itoa(i), Type: "t" + strconv.Itoa(i%3), Role: "r" + strconv.Itoa(i%5)})
+	}
+
+	result := m.GetByIndexBatch([]IndexQuery{
+		{Name: "Type", Value: "t0"},
+		{Name: "Role", Value: "R1"},
+		{Name: "Type", Value: "missing"},
+		{Name: "Color", Value: "red"},
+	})
+	assert.Equal(t, 4, len(result))
+	assert.Equal(t, 10, len(result[0]))
+	assert.Equal(t, 6, len(result[1]))
+	assert.Equal(t, 0, len(result[2]))
+	assert.Equal(t, 0, len(result[3]))
+	assert.Equal(t, 3, len(m.GetIndexKeys("Type")))
+
+	assert.Equal(t, 0, len(m.GetByIndexBatch(nil)))
+}
+
+func TestGetByIndexSorted(t *testing.T) {
+	m := NewAnimalMap()
+
+	m.PutInt(1, Animal{Id: 1, Name: "Dog", Type: "small"})
+	m.PutInt(2, Animal{Id: 2, Name: "Cat", Type: "small"})
+	m.PutInt(3, Animal{Id: 3, Name: "Rat", Type: "small"})
+	m.PutInt(4, Animal{Id: 4, Name: "Ant", Type: "big"})
+
+	list := m.GetByIndexSorted("Type", "small", func(a, b Animal) bool {
+		return a.Name < b.Name
+	})
+	assert.Equal(t, 3, len(list))
+	assert.Equal(t, "Cat", list[0].Name)
+	assert.Equal(t, "Dog", list[1].Name)
+	assert.Equal(t, "Rat", list[2].Name)
+
+	assert.Equal(t, 0, len(m.GetByIndexSorted("Type", "huge", func(a, b Animal) bool { return true })))
+}
+
+func TestGetKeysByIndex(t *testing.T) {
+	m := NewAnimalMap()
+
+	m.Put("cat", Animal{Id: 1, Name: "Cat", Type: "small"})
+	m.Put("dog", Animal{Id: 2, Name: "Dog", Type: "small"})
+	m.Put("cow", Animal{Id: 3, Name: "Cow", Type: "big"})
+
+	assert.ElementsMatch(t, []string{"cat", "dog"}, m.GetKeysByIndex("Type", "Small"))
+	assert.Equal(t, []string{}, m.GetKeysByIndex("Type", "huge"))
+	assert.Equal(t, []string{}, m.GetKeysByIndex("Color", "red"))
+	assert.Equal(t, 2, len(m.GetIndexKeys("Type")))
+}
+
+func TestGetKeysByIndexWithCount(t *testing.T) {
+	m := NewAnimalMap()
+
+	m.Put("cat", Animal{Id: 1, Name: "Cat", Type: "small"})
+	m.Put("dog", Animal{Id: 2, Name: "Dog", Type: "small"})
+	m.Put("rat", Animal{Id: 3, Name: "Rat", Type: "small"})
+	m.Put("cow", Animal{Id: 4, Name: "Cow", Type: "big"})
+
+	keys, total := m.GetKeysByIndexWithCount("Type", "Small", 2)
+	assert.Equal(t, 3, total)
+	assert.Equal(t, 2, len(keys))
+	assert.Subset(t, []string{"cat", "dog", "rat"}, keys)
+
+	keys, total = m.GetKeysByIndexWithCount("Type", "big", 10)
+	assert.Equal(t, []string{"cow"}, keys)
+	assert.Equal(t, 1, total)
+
+	keys, total = m.GetKeysByIndexWithCount("Type", "small", 0)
+	assert.Equal(t, []string{}, keys)
+	assert.Equal(t, 3, total)
+
+	keys, total = m.GetKeysByIndexWithCount("Color", "red", 10)
+	assert.Equal(t, []string{}, keys)
+	assert.Equal(t, 0, total)
+}
+
+func TestWalkIndex(t *testing.T) {
+	m := NewAnimalMap()
+
+	m.PutInt(1, Animal{Id: 1, Name: "Cow", Type: "big"})
+	m.PutInt(2, Animal{Id: 2, Name: "Horse", Type: "big"})
+	m.PutInt(3, Animal{Id: 3, Name: "Cat", Type: "small"})
+	m.PutInt(4, Animal{Id: 4, Name: "Whale", Type: "huge"})
+	m.RemoveInt(4)
+
+	groups := map[string]int{}
+	m.WalkIndex("Type", func(value string, members []Animal) bool {
+		groups[value] = len(members)
+		return true
+	})
+	assert.Equal(t, map[string]int{"BIG": 2, "SMALL": 1}, groups)
+
+	calls := 0
+	m.WalkIndex("Type", func(value string, members []Animal) bool {
+		calls++
+		return false
+	})
+	assert.Equal(t, 1, calls)
+
+	m.WalkIndex("Color", func(value string, members []Animal) bool {
+		assert.Fail(t, "unknown index has no values")
+		return true
+	})
+}
+
+func TestGetByIndexParallel(t *testing.T) {
+	m := NewAnimalMap()
+	for i := range 25000 {
+		typ := "common"
+		if i%1000 == 0 {
+			typ = "rare"
+		}
+		m.PutInt(i, Animal{Id: i, Type: typ})
+	}
+
+	list := m.GetByIndexParallel("Type", "common")
+	assert.Equal(t, 24975, len(list))
+	ids := map[int]struct{}{}
+	for _, a := range list {
+		ids[a.Id] = struct{}{}
+	}
+	assert.Equal(t, 24975, len(ids))
+
+	assert.Equal(t, 25, len(m.GetByIndexParallel("Type", "RARE")))
+	assert.Equal(t, []Animal{}, m.GetByIndexParallel("Type", "huge"))
+	assert.Equal(t, []Animal{}, m.GetByIndexParallel("Color", "red"))
+}
+
+func TestGetByIndexWithKeys(t *testing.T) {
+	m := NewAnimalMap()
+
+	m.Put("cat", Animal{Id: 1, Name: "Cat", Type: "small"})
+	m.Put("dog", Animal{Id: 2, Name: "Dog", Type: "small"})
+	m.Put("cow", Animal{Id: 3, Name: "Cow", Type: "big"})
+
+	result := m.GetByIndexWithKeys("Type", "Small")
+	assert.Equal(t, 2, len(result))
+	assert.Equal(t, "Cat", result["cat"].Name)
+	assert.Equal(t, "Dog", result["dog"].Name)
+
+	m.GetByIndexUnderlyingMap("Type", "small").Store("MOUSE", struct{}{})
+	assert.Equal(t, 2, len(m.GetByIndexWithKeys("Type", "small")))
+	assert.Equal(t, map[string]Animal{}, m.GetByIndexWithKeys("Type", "huge"))
+	assert.Equal(t, map[string]Animal{}, m.GetByIndexWithKeys("Color", "red"))
+}
+
+func TestGetByIndexInto(t *testing.T) {
+	m := NewAnimalMap()
+
+	m.PutInt(1, Animal{Id: 1, Name: "Cow", Type: "big"})
+	m.PutInt(2, Animal{Id: 2, Name: "Horse", Type: "big"})
+	m.PutInt(3, Animal{Id: 3, Name: "Cat", Type: "small"})
+
+	buf := make([]Animal, 0, 10)
+	buf = m.GetByIndexInto("Type", "Big", buf)
+	assert.Equal(t, 2, len(buf))
+	assert.Equal(t, 10, cap(buf))
+
+	buf = m.GetByIndexInto("Type", "small", buf)
+	assert.Equal(t, 1, len(buf))
+	assert.Equal(t, "Cat", buf[0].Name)
+
+	buf = m.GetByIndexInto("Type", "huge", buf)
+	assert.Equal(t, 0, len(buf))
+	assert.Equal(t, 2, len(m.GetIndexKeys("Type")))
+
+	assert.Equal(t, 1, len(m.GetByIndexInto("Type", "small", nil)))
+}
+
+func TestRangeByIndex(t *testing.T) {
+	m := NewAnimalMap()
+
+	for i := range 10 {
+		m.PutInt(i, Animal{Id: i, Name: "animal" + strconv.Itoa(i), Type: "big"})
+	}
+
+	count := 0
+	m.RangeByIndex("Type", "Big", func(a Animal) bool {
+		count++
+		return true
+	})
+	assert.Equal(t, 10, count)
+
+	count = 0
+	m.RangeByIndex("Type", "big", func(a Animal) bool {
+		count++
+		return count < 4
+	})
+	assert.Equal(t, 4, count)
+
+	m.RangeByIndex("Type", "small", func(a Animal) bool {
+		t.Fatal("unexpected element")
+		return true
+	})
+	assert.Equal(t, 1, len(m.GetIndexKeys("Type")))
+}
+
+func BenchmarkPutAllSized(b *testing.B) {
+	data := make([]Animal, 0, 1000000)
+	for i := range cap(data) {
+		data = append(data, Animal{Id: i, Type: "t" + strconv.Itoa(i%10)})
+	}
+	keyFunc := func(a *Animal) string { return strconv.Itoa(a.Id) }
+	indexes := map[string]IndexFunc[Animal]{
+		"Type": func(a *Animal) string {
+			return a.Type
+		},
+	}
+	b.Run("Default", func(b *testing.B) {
+		for range b.N {
+			NewIndexedMap(indexes).PutAll(data, keyFunc)
+		}
+	})
+	b.Run("Sized", func(b *testing.B) {
+		for range b.N {
+			NewIndexedMapSized(indexes, len(data)).PutAll(data, keyFunc)
+		}
+	})
+}
+
+func BenchmarkPutInt(b *testing.B) {
+	m := NewAnimalMap()
+	b.RunParallel(func(pb *testing.PB) {
+		n := 0
+		for pb.Next() {
+			m.PutInt(n%890000, Animal{Id: n, Type: "one"})
+			n++
+		}
+	})
+}
+
+// Bounded map checks Size on every put, which must not range elements put with TTL
+func BenchmarkPutBoundedWithTTL(b *testing.B) {
+	m := NewBoundedAnimalMap(100000)
+	for i := range 50000 {
+		m.PutWithTTL(strconv.Itoa(i), Animal{Id: i, Type: "one"}, time.Hour)
+	}
+	b.ResetTimer()
+	for i := range b.N {
+		m.PutInt(50000+i%50000, Animal{Id: i, Type: "two"})
+	}
+}
+
+// Many goroutines bulk loading the same map at once
+func BenchmarkPutAllRepeated(b *testing.B) {
+	data := make([]Animal, 0, 20000)
+	for i := range cap(data) {
+		data = append(data, Animal{Id: i, Type: "t" + strconv.Itoa(i%10)})
+	}
+	keyFunc := func(a *Animal) string { return strconv.Itoa(a.Id) }
+	m := NewAnimalMap()
+	b.SetParallelism(8)
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			m.PutAll(data, keyFunc)
+		}
+	})
+}
+
+func BenchmarkPutStringKey(b *testing.B) {
+	m := NewAnimalMap()
+	b.RunParallel(func(pb *testing.PB) {
+		n := 0
+		for pb.Next() {
+			m.Put(strconv.Itoa(n%890000), Animal{Id: n, Type: "one"})
+			n++
+		}
+	})
+}
+
+func BenchmarkPutSame(b *testing.B) {
+	x := Animal{Id: 1, Name: "Dog", Type: "big", Role: "pet"}
+	b.Run("Default", func(b *testing.B) {
+		m := NewAnimalMap()
+		for range b.N {
+			m.PutInt(x.Id, x)
+		}
+	})
+	b.Run("Equals", func(b *testing.B) {
+		m := NewIndexedMapWithOptions(map[string]IndexFunc[Animal]{
+			"Type": func(a *Animal) string {
+				return a.Type
+			},
+			"Role": func(a *Animal) string {
+				return a.Role
+			},
+			"NumType": func(a *Animal) string {
+				return strconv.Itoa(a.NumType)
+			},
+			"RoleType": func(a *Animal) string {
+				return a.RoleType()
+			},
+		}, Options[Animal]{Equals: func(a, b Animal) bool { return a == b }})
+		for range b.N {
+			m.PutInt(x.Id, x)
+		}
+	})
+}
+
+func BenchmarkGetByIndexLarge(b *testing.B) {
+	m := NewAnimalMap()
+	for i := range 500000 {
+		m.PutInt(i, Animal{Id: i, Type: "common"})
+	}
+	b.Run("Serial", func(b *testing.B) {
+		for range b.N {
+			m.GetByIndex("Type", "common")
+		}
+	})
+	b.Run("Parallel", func(b *testing.B) {
+		for range b.N {
+			m.GetByIndexParallel("Type", "common")
+		}
+	})
+}
+
+func BenchmarkGetInt(b *testing.B) {
+	m := NewAnimalMap()
+	for i := range 1000 {
+		m.PutInt(i, Animal{Id: i, Type: "one"})
+	}
+	b.ResetTimer()
+	for i := range b.N {
+		m.GetInt(i % 1000)
+	}
+}
@@ -0,0 +1,46 @@
+//go:build go1.23
+
+package indexedmap
+
+import (
+	"iter"
+
+	"github.com/puzpuzpuz/xsync/v3"
+)
+
+// Iterate over all elements of the map as primary key and element pairs.
+// Elements are yielded by value, consistency is the same as in ForEach.
+func (r *IndexedMap[T]) All() iter.Seq2[string, T] {
+	return func(yield func(string, T) bool) {
+		r.ForEach(yield)
+	}
+}
+
+// Iterate over elements having index value.
+// Elements are yielded lazily while ranging the index value collection.
+func (r *IndexedMap[T]) ByIndex(name string, v string) iter.Seq[T] {
+	return func(yield func(T) bool) {
+		r.RangeByIndex(name, v, yield)
+	}
+}
+
+// Iterate over values of index together with lazy sequences of elements having them.
+// Unlike WalkIndex, elements are never materialized, and values which have no elements
+// when the iteration reaches them are skipped.
+func (r *IndexedMap[T]) IndexGroups(name string) iter.Seq2[string, iter.Seq[T]] {
+	return func(yield func(string, iter.Seq[T]) bool) {
+		r.walkIndex(name, func(value string, m *xsync.Map) bool {
+			if m.Size() == 0 {
+				return true
+			}
+			return yield(value, func(yield func(T) bool) {
+				m.Range(func(k string, _ any) bool {
+					if obj, ok := r.lookup(k); ok {
+						return yield(obj)
+					}
+					return true
+				})
+			})
+		})
+	}
+}
@@ -0,0 +1,94 @@
+//go:build go1.23
+
+package indexedmap
+
+import (
+	"slices"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAll(t *testing.T) {
+	m := NewAnimalMap()
+
+	for i := range 10 {
+		m.PutInt(i, Animal{Id: i, Name: "animal" + strconv.Itoa(i)})
+	}
+
+	count := 0
+	for key, a := range m.All() {
+		assert.Equal(t, strconv.Itoa(a.Id), key)
+		count++
+	}
+	assert.Equal(t, 10, count)
+
+	count = 0
+	for range m.All() {
+		count++
+		if count == 4 {
+			break
+		}
+	}
+	assert.Equal(t, 4, count)
+}
+
+func TestByIndex(t *testing.T) {
+	m := NewAnimalMap()
+
+	m.PutInt(1, Animal{Id: 1, Name: "Cow", Type: "big"})
+	m.PutInt(2, Animal{Id: 2, Name: "Horse", Type: "big"})
+	m.PutInt(3, Animal{Id: 3, Name: "Cat", Type: "small"})
+
+	names := []string{}
+	for a := range m.ByIndex("Type", "Big") {
+		names = append(names, a.Name)
+	}
+	slices.Sort(names)
+	assert.Equal(t, []string{"Cow", "Horse"}, names)
+
+	assert.Equal(t, 1, len(slices.Collect(m.ByIndex("Type", "small"))))
+	assert.Equal(t, 0, len(slices.Collect(m.ByIndex("Type", "huge"))))
+	assert.Equal(t, 2, len(m.GetIndexKeys("Type")))
+}
+
+func TestIndexGroups(t *testing.T) {
+	m := NewAnimalMap()
+
+	m.PutInt(1, Animal{Id: 1, Name: "Cow", Type: "big"})
+	m.PutInt(2, Animal{Id: 2, Name: "Horse", Type: "big"})
+	m.PutInt(3, Animal{Id: 3, Name: "Cat", Type: "small"})
+	m.PutInt(4, Animal{Id: 4, Name: "Whale", Type: "huge"})
+	m.RemoveInt(4)
+
+	groups := map[string]int{}
+	for value, members := range m.IndexGroups("Type") {
+		groups[value] = len(slices.Collect(members))
+	}
+	assert.Equal(t, map[string]int{"BIG": 2, "SMALL": 1}, groups)
+
+	for range m.IndexGroups("Color") {
+		assert.Fail(t, "unknown index has no groups")
+	}
+}
+
+func TestReadOnlyIterators(t *testing.T) {
+	m := NewAnimalMap()
+	m.PutInt(1, Animal{Id: 1, Name: "Cow", Type: "big"})
+	m.PutInt(2, Animal{Id: 2, Name: "Cat", Type: "small"})
+
+	v := m.ReadOnly()
+	keys := []string{}
+	for key := range v.All() {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	assert.Equal(t, []string{"1", "2"}, keys)
+
+	names := []string{}
+	for a := range v.ByIndex("Type", "small") {
+		names = append(names, a.Name)
+	}
+	assert.Equal(t, []string{"Cat"}, names)
+}
@@ -0,0 +1,123 @@
+package indexedmap
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+var errNotConstructed = errors.New("indexedmap: unmarshalling into map which is not created by constructor")
+
+// Serialize primary index data as JSON object of primary keys and elements.
+// Secondary indexes are not serialized, they are rebuilt on unmarshalling.
+func (r *IndexedMap[T]) MarshalJSON() ([]byte, error) {
+	data := make(map[string]T, r.Size())
+	r.ForEach(func(key string, value T) bool {
+		data[key] = value
+		return true
+	})
+	return json.Marshal(data)
+}
+
+// Load elements from JSON object of primary keys and elements.
+// Index functions can't be serialized, so the map must be created by one of constructors
+// with the same indexes before unmarshalling. Secondary indexes are rebuilt by Put.
+// Like for regular Go maps, elements already present in the map are kept unless overwritten.
+// Returns ErrClosed if the map is closed.
+func (r *IndexedMap[T]) UnmarshalJSON(b []byte) error {
+	if r.primary == nil {
+		return errNotConstructed
+	}
+	if r.closed.Load() {
+		return ErrClosed
+	}
+	var data map[string]T
+	if err := json.Unmarshal(b, &data); err != nil {
+		return err
+	}
+	for key, value := range data {
+		if err := r.putLoaded(key, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Write the same JSON object as MarshalJSON to w, streaming elements one by one,
+// so neither the whole map nor its JSON is ever held in memory.
+// Like Save, it ranges the map without blocking writers.
+func (r *IndexedMap[T]) WriteJSON(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	bw.WriteByte('{')
+	var err error
+	first := true
+	r.ForEach(func(key string, value T) bool {
+		var k, v []byte
+		if k, err = json.Marshal(key); err != nil {
+			return false
+		}
+		if v, err = json.Marshal(value); err != nil {
+			return false
+		}
+		if !first {
+			bw.WriteByte(',')
+		}
+		first = false
+		bw.Write(k)
+		bw.WriteByte(':')
+		_, err = bw.Write(v)
+		return err == nil
+	})
+	if err != nil {
+		return err
+	}
+	bw.WriteByte('}')
+	return bw.Flush()
+}
+
+// Read JSON object of primary keys and elements from rd, e.g. written by WriteJSON,
+// decoding and putting elements one by one. Requirements are the same as for UnmarshalJSON.
+// Elements read before a decoding error are kept in the map. Returns ErrClosed if the map is closed.
+func (r *IndexedMap[T]) ReadJSON(rd io.Reader) error {
+	if r.primary == nil {
+		return errNotConstructed
+	}
+	if r.closed.Load() {
+		return ErrClosed
+	}
+	dec := json.NewDecoder(rd)
+	if err := expectDelim(dec, '{'); err != nil {
+		return err
+	}
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		key, ok := tok.(string)
+		if !ok {
+			return fmt.Errorf("indexedmap: expected JSON object key, got %v", tok)
+		}
+		var value T
+		if err := dec.Decode(&value); err != nil {
+			return err
+		}
+		if err := r.putLoaded(key, value); err != nil {
+			return err
+		}
+	}
+	return expectDelim(dec, '}')
+}
+
+func expectDelim(dec *json.Decoder, delim json.Delim) error {
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if d, ok := tok.(json.Delim); !ok || d != delim {
+		return fmt.Errorf("indexedmap: expected %v in JSON, got %v", delim, tok)
+	}
+	return nil
+}
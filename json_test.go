@@ -0,0 +1,72 @@
+package indexedmap
+
+import (
+	"bytes"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJSON(t *testing.T) {
+	m := NewAnimalMap()
+
+	m.Put("cat", Animal{Id: 1, Name: "Cat", Type: "small", Role: "pet"})
+	m.Put("cow", Animal{Id: 2, Name: "Cow", Type: "big", Role: "farm"})
+	m.Put("dog", Animal{Id: 3, Name: "Dog", Type: "small", Role: "pet"})
+
+	b, err := json.Marshal(m)
+	assert.NoError(t, err)
+
+	n := NewAnimalMap()
+	assert.NoError(t, json.Unmarshal(b, n))
+
+	assert.Equal(t, m.Size(), n.Size())
+	assert.ElementsMatch(t, m.Keys(), n.Keys())
+	assert.ElementsMatch(t, m.GetByIndex("Type", "small"), n.GetByIndex("Type", "small"))
+	assert.ElementsMatch(t, m.GetByIndex("Role", "farm"), n.GetByIndex("Role", "farm"))
+	assert.ElementsMatch(t, m.GetIndexKeys("RoleType"), n.GetIndexKeys("RoleType"))
+
+	a, ok := n.Get("Cat")
+	assert.True(t, ok)
+	assert.Equal(t, "Cat", a.Name)
+
+	assert.Error(t, json.Unmarshal([]byte(`[1, 2]`), n))
+	assert.Error(t, json.Unmarshal(b, &IndexedMap[Animal]{}))
+}
+
+func TestWriteReadJSON(t *testing.T) {
+	m := NewAnimalMap()
+
+	for i := range 1000 {
+		m.PutInt(i, Animal{Id: i, Name: "animal" + strconv.Itoa(i), Type: "t" + strconv.Itoa(i%3), Role: "r" + strconv.Itoa(i%5)})
+	}
+
+	var buf bytes.Buffer
+	assert.NoError(t, m.WriteJSON(&buf))
+	b, err := json.Marshal(m)
+	assert.NoError(t, err)
+	assert.JSONEq(t, string(b), buf.String())
+
+	n := NewAnimalMap()
+	assert.NoError(t, n.ReadJSON(&buf))
+	assert.Equal(t, m.Size(), n.Size())
+	assert.ElementsMatch(t, m.GetByIndex("Type", "t1"), n.GetByIndex("Type", "t1"))
+	assert.ElementsMatch(t, m.GetIndexKeys("RoleType"), n.GetIndexKeys("RoleType"))
+
+	e := NewAnimalMap()
+	buf.Reset()
+	assert.NoError(t, e.WriteJSON(&buf))
+	assert.Equal(t, "{}", buf.String())
+	assert.NoError(t, e.ReadJSON(&buf))
+	assert.True(t, e.IsEmpty())
+
+	assert.Error(t, m.WriteJSON(failingWriter{}))
+	assert.Error(t, e.ReadJSON(strings.NewReader(`[1, 2]`)))
+	assert.Error(t, e.ReadJSON(strings.NewReader(`{"cat": {"Id": 1}, "cow": {"Id": "x"}}`)))
+	assert.Equal(t, 1, e.Size())
+	assert.Error(t, e.ReadJSON(strings.NewReader(`{"dog": {"Id": 3}`)))
+	assert.Error(t, (&IndexedMap[Animal]{}).ReadJSON(strings.NewReader(`{}`)))
+}
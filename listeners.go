@@ -0,0 +1,90 @@
+package indexedmap
+
+import "slices"
+
+// Kind of change made to map element.
+type ChangeOp int
+
+const (
+	// New element was added
+	OpInsert ChangeOp = iota
+	// Existing element was replaced
+	OpUpdate
+	// Element was removed
+	OpDelete
+	// All elements were removed by Clear, event has no key and elements
+	OpClear
+)
+
+// Change of single map element passed to listeners.
+type ChangeEvent[T any] struct {
+	Op  ChangeOp
+	Key string
+
+	// Previous element, zero value for OpInsert
+	Old T
+
+	// New element, zero value for OpDelete
+	New T
+}
+
+// Change listener registered with OnChange, compared by pointer on removal.
+type listener[T any] struct {
+	fn func(ChangeEvent[T])
+}
+
+// Register listener called on every element insert, update and removal.
+// Listeners are called synchronously by the goroutine which made the change,
+// after primary index is updated and without holding internal locks,
+// so they may call methods of the map. Clear produces single OpClear event instead of removal of every element.
+// Returns function unregistering the listener, calling it more than once does nothing.
+func (r *IndexedMap[T]) OnChange(fn func(event ChangeEvent[T])) func() {
+	l := &listener[T]{fn: fn}
+	r.listenersMu.Lock()
+	defer r.listenersMu.Unlock()
+	var listeners []*listener[T]
+	if current := r.listeners.Load(); current != nil {
+		listeners = append(listeners, *current...)
+	}
+	listeners = append(listeners, l)
+	r.listeners.Store(&listeners)
+	return func() {
+		r.removeListener(l)
+	}
+}
+
+func (r *IndexedMap[T]) removeListener(l *listener[T]) {
+	r.listenersMu.Lock()
+	defer r.listenersMu.Unlock()
+	current := r.listeners.Load()
+	if current == nil {
+		return
+	}
+	listeners := slices.DeleteFunc(slices.Clone(*current), func(other *listener[T]) bool {
+		return other == l
+	})
+	r.listeners.Store(&listeners)
+}
+
+func (r *IndexedMap[T]) notify(events ...ChangeEvent[T]) {
+	l := r.listeners.Load()
+	if l == nil {
+		return
+	}
+	for _, e := range events {
+		for _, listener := range *l {
+			listener.fn(e)
+		}
+	}
+}
+
+func (r *IndexedMap[T]) notifyPut(key string, prev, obj T, loaded bool) {
+	if r.listeners.Load() == nil {
+		return
+	}
+	if loaded {
+		r.notify(ChangeEvent[T]{Op: OpUpdate, Key: key, Old: prev, New: obj})
+	} else {
+		r.notify(ChangeEvent[T]{Op: OpInsert, Key: key, New: obj})
+	}
+}
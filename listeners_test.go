@@ -0,0 +1,74 @@
+package indexedmap
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOnChange(t *testing.T) {
+	m := NewAnimalMap()
+
+	var mu sync.Mutex
+	events := []ChangeEvent[Animal]{}
+	m.OnChange(func(e ChangeEvent[Animal]) {
+		mu.Lock()
+		defer mu.Unlock()
+		events = append(events, e)
+	})
+	second := 0
+	m.OnChange(func(e ChangeEvent[Animal]) {
+		// listeners are called without internal locks, so they can use the map
+		assert.Equal(t, e.Op != OpDelete, m.ContainsKey(e.Key))
+		second++
+	})
+
+	m.PutInt(1, Animal{Id: 1, Name: "Cat", Type: "small"})
+	m.Put("1", Animal{Id: 1, Name: "Cat", Type: "big"})
+	m.RemoveInt(1)
+	m.RemoveInt(1)
+	m.PutIfAbsent("2", Animal{Id: 2, Name: "Dog", Type: "big"})
+	m.PutIfAbsent("2", Animal{Id: 2, Name: "Dog", Type: "big"})
+	m.Update("2", func(a *Animal) bool {
+		a.Type = "small"
+		return true
+	})
+	m.RemoveByIndex("Type", "small")
+
+	assert.Equal(t, 6, len(events))
+	assert.Equal(t, 6, second)
+
+	assert.Equal(t, ChangeEvent[Animal]{Op: OpInsert, Key: "1", New: Animal{Id: 1, Name: "Cat", Type: "small"}}, events[0])
+	assert.Equal(t, OpUpdate, events[1].Op)
+	assert.Equal(t, "small", events[1].Old.Type)
+	assert.Equal(t, "big", events[1].New.Type)
+	assert.Equal(t, OpDelete, events[2].Op)
+	assert.Equal(t, "big", events[2].Old.Type)
+	assert.Equal(t, OpInsert, events[3].Op)
+	assert.Equal(t, OpUpdate, events[4].Op)
+	assert.Equal(t, "small", events[4].New.Type)
+	assert.Equal(t, ChangeEvent[Animal]{Op: OpDelete, Key: "2", Old: Animal{Id: 2, Name: "Dog", Type: "small"}}, events[5])
+}
+
+func TestOnChangeUnregister(t *testing.T) {
+	m := NewAnimalMap()
+
+	first, second := []ChangeOp{}, []ChangeOp{}
+	unregister := m.OnChange(func(e ChangeEvent[Animal]) {
+		first = append(first, e.Op)
+	})
+	m.OnChange(func(e ChangeEvent[Animal]) {
+		second = append(second, e.Op)
+	})
+
+	m.PutInt(1, Animal{Id: 1, Name: "Cat", Type: "small"})
+	m.PutInt(2, Animal{Id: 2, Name: "Dog", Type: "small"})
+	m.Clear()
+	unregister()
+	unregister()
+	m.PutInt(1, Animal{Id: 1, Name: "Cat", Type: "small"})
+
+	assert.Equal(t, []ChangeOp{OpInsert, OpInsert, OpClear}, first)
+	assert.Equal(t, []ChangeOp{OpInsert, OpInsert, OpClear, OpInsert}, second)
+}
@@ -0,0 +1,91 @@
+package indexedmap
+
+import (
+	"container/list"
+	"sync"
+)
+
+// Access order of primary keys kept for maps with MaxSize option, most recently used first.
+// The list is guarded by single mutex, so every Get and Put of bounded map takes it:
+// reads don't scale across cores like in unbounded map, that's the price of exact LRU order.
+type lruList struct {
+	mu    sync.Mutex
+	order *list.List
+	elems map[string]*list.Element
+}
+
+func newLRUList() *lruList {
+	return &lruList{order: list.New(), elems: map[string]*list.Element{}}
+}
+
+// Mark key as the most recently used, adding it if it's not tracked yet.
+func (l *lruList) add(key string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if e, ok := l.elems[key]; ok {
+		l.order.MoveToFront(e)
+		return
+	}
+	l.elems[key] = l.order.PushFront(key)
+}
+
+// Mark key as the most recently used if it's tracked.
+func (l *lruList) touch(key string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if e, ok := l.elems[key]; ok {
+		l.order.MoveToFront(e)
+	}
+}
+
+func (l *lruList) remove(key string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if e, ok := l.elems[key]; ok {
+		l.order.Remove(e)
+		delete(l.elems, key)
+	}
+}
+
+// Get the least recently used key.
+func (l *lruList) oldest() (string, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	e := l.order.Back()
+	if e == nil {
+		return "", false
+	}
+	return e.Value.(string), true
+}
+
+func (l *lruList) clear() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.order.Init()
+	clear(l.elems)
+}
+
+// Mark element as used for bounded map.
+func (r *IndexedMap[T]) touch(key string) {
+	if r.lru != nil {
+		r.lru.touch(key)
+	}
+}
+
+// Remove least recently used elements while the map holds more than MaxSize of them.
+// Called by writers after releasing their locks, as removal takes lock of another key.
+func (r *IndexedMap[T]) evict() {
+	if r.lru == nil {
+		return
+	}
+	for r.Size() > r.opts.MaxSize {
+		key, ok := r.lru.oldest()
+		if !ok {
+			return
+		}
+		if _, ok := r.removeKey(key); !ok {
+			// removed concurrently, so it isn't tracked anymore
+			r.lru.remove(key)
+		}
+	}
+}
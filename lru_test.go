@@ -0,0 +1,75 @@
+package indexedmap
+
+import (
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func NewBoundedAnimalMap(maxSize int) *IndexedMap[Animal] {
+	return NewIndexedMapWithOptions(map[string]IndexFunc[Animal]{
+		"Type": func(a *Animal) string {
+			return a.Type
+		},
+	}, Options[Animal]{MaxSize: maxSize})
+}
+
+func TestMaxSize(t *testing.T) {
+	m := NewBoundedAnimalMap(3)
+	evicted := []string{}
+	m.OnChange(func(e ChangeEvent[Animal]) {
+		if e.Op == OpDelete {
+			evicted = append(evicted, e.Key)
+		}
+	})
+
+	m.PutInt(1, Animal{Id: 1, Name: "Cat", Type: "small"})
+	m.PutInt(2, Animal{Id: 2, Name: "Dog", Type: "small"})
+	m.PutInt(3, Animal{Id: 3, Name: "Cow", Type: "big"})
+	_, ok := m.GetInt(1)
+	assert.True(t, ok)
+
+	m.PutInt(4, Animal{Id: 4, Name: "Horse", Type: "big"})
+	assert.Equal(t, 3, m.Size())
+	assert.Equal(t, []string{"2"}, evicted)
+	assert.Equal(t, 1, len(m.GetByIndex("Type", "small")))
+
+	m.PutInt(3, Animal{Id: 3, Name: "Cow", Type: "big"})
+	m.PutIfAbsent("5", Animal{Id: 5, Name: "Mouse", Type: "small"})
+	assert.Equal(t, []string{"2", "1"}, evicted)
+	assert.ElementsMatch(t, []string{"3", "4", "5"}, m.Keys())
+
+	m.RemoveInt(4)
+	m.PutInt(6, Animal{Id: 6, Name: "Fox", Type: "small"})
+	assert.Equal(t, []string{"2", "1", "4"}, evicted)
+	assert.Equal(t, 3, m.Size())
+
+	m.Clear()
+	m.PutInt(7, Animal{Id: 7, Name: "Owl", Type: "small"})
+	assert.Equal(t, 1, m.Size())
+}
+
+func TestMaxSizeConcurrent(t *testing.T) {
+	m := NewBoundedAnimalMap(100)
+
+	var wg sync.WaitGroup
+	for w := range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range 2000 {
+				id := w*2000 + i
+				m.PutInt(id, Animal{Id: id, Type: "t" + strconv.Itoa(i%3)})
+				m.GetInt(id - 1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.LessOrEqual(t, m.Size(), 100)
+	assert.Equal(t, m.ExactSize(), m.Size())
+	assert.Equal(t, m.Size(), len(m.lru.elems))
+	assert.Nil(t, m.Validate())
+}
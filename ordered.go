@@ -0,0 +1,146 @@
+package indexedmap
+
+import (
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/google/btree"
+)
+
+// Sorted set of index values kept by ordered indexes next to hash-based value collections.
+// Only distinct values are stored in the tree, so it's touched by writers
+// just when index value appears for the first time, not on every Put.
+type sortedValues struct {
+	mu   sync.RWMutex
+	tree *btree.BTreeG[string]
+}
+
+func newSortedValues() *sortedValues {
+	return &sortedValues{tree: btree.NewOrderedG[string](32)}
+}
+
+func (s *sortedValues) add(v string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tree.ReplaceOrInsert(v)
+}
+
+func (s *sortedValues) remove(v string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tree.Delete(v)
+}
+
+func (s *sortedValues) clear() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tree.Clear(false)
+}
+
+// Get sorted values in [lo, hi] range.
+func (s *sortedValues) between(lo, hi string) []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	result := []string{}
+	s.tree.AscendGreaterOrEqual(lo, func(v string) bool {
+		if v > hi {
+			return false
+		}
+		result = append(result, v)
+		return true
+	})
+	return result
+}
+
+// Get sorted values starting with prefix.
+func (s *sortedValues) withPrefix(prefix string) []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	result := []string{}
+	s.tree.AscendGreaterOrEqual(prefix, func(v string) bool {
+		if !strings.HasPrefix(v, prefix) {
+			return false
+		}
+		result = append(result, v)
+		return true
+	})
+	return result
+}
+
+func (s *sortedValues) all() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	result := make([]string, 0, s.tree.Len())
+	s.tree.Ascend(func(v string) bool {
+		result = append(result, v)
+		return true
+	})
+	return result
+}
+
+// Find all elements with index values in [lo, hi] range, ordered by index value.
+// Ordered indexes (see Options.OrderedIndexes) look values up in a B-tree,
+// other indexes fall back to scanning all index values.
+// Values are compared as normalized strings, so numbers should be zero-padded to the same width.
+func (r *IndexedMap[T]) GetByIndexRange(name, lo, hi string) []T {
+	t := r.mu.RLock()
+	defer r.mu.RUnlock(t)
+	lo, hi = r.normalize(lo), r.normalize(hi)
+	var values []string
+	if s, ok := r.ordered[name]; ok {
+		values = s.between(lo, hi)
+	} else {
+		for _, v := range r.indexKeys(name) {
+			if v >= lo && v <= hi {
+				values = append(values, v)
+			}
+		}
+		slices.Sort(values)
+	}
+	result := []T{}
+	for _, v := range values {
+		if m, ok := r.findIndexMapList(name, v); ok {
+			m.Range(func(k string, _ any) bool {
+				if obj, ok := r.lookup(k); ok {
+					result = append(result, obj)
+				}
+				return true
+			})
+		}
+	}
+	return result
+}
+
+// Get all values of index in ascending order.
+// Ordered indexes keep values sorted, for other indexes values are sorted on every call.
+func (r *IndexedMap[T]) GetIndexKeysSorted(name string) []string {
+	t := r.mu.RLock()
+	defer r.mu.RUnlock(t)
+	if s, ok := r.ordered[name]; ok {
+		return s.all()
+	}
+	result := r.indexKeys(name)
+	slices.Sort(result)
+	return result
+}
+
+// Find all elements with index values starting with prefix.
+// Element matching several values is returned once.
+// Ordered indexes find matching values in a B-tree, other indexes scan all index values.
+func (r *IndexedMap[T]) GetByIndexPrefix(name, prefix string) []T {
+	t := r.mu.RLock()
+	defer r.mu.RUnlock(t)
+	prefix = r.normalize(prefix)
+	var values []string
+	if s, ok := r.ordered[name]; ok {
+		values = s.withPrefix(prefix)
+	} else {
+		for _, v := range r.indexKeys(name) {
+			if strings.HasPrefix(v, prefix) {
+				values = append(values, v)
+			}
+		}
+	}
+	return r.collectByIndexValues(name, values)
+}
@@ -0,0 +1,107 @@
+package indexedmap
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type Order struct {
+	Id     int
+	Amount int
+	Status string
+}
+
+func NewOrderMap() *IndexedMap[Order] {
+	return NewIndexedMapWithOptions(map[string]IndexFunc[Order]{
+		"Amount": func(o *Order) string {
+			return fmt.Sprintf("%08d", o.Amount)
+		},
+		"Status": func(o *Order) string {
+			return o.Status
+		},
+	}, Options[Order]{OrderedIndexes: []string{"Amount"}})
+}
+
+func TestGetByIndexRange(t *testing.T) {
+	m := NewOrderMap()
+
+	for i := range 100 {
+		m.PutInt(i, Order{Id: i, Amount: (i % 50) * 10, Status: fmt.Sprintf("s%02d", i%10)})
+	}
+
+	list := m.GetByIndexRange("Amount", "00000100", "00000200")
+	assert.Equal(t, 22, len(list))
+	for i := 1; i < len(list); i++ {
+		assert.LessOrEqual(t, list[i-1].Amount, list[i].Amount)
+	}
+
+	m.RemoveInt(10)
+	m.PutInt(60, Order{Id: 60, Amount: 5000})
+	assert.Equal(t, 20, len(m.GetByIndexRange("Amount", "00000100", "00000200")))
+	assert.Equal(t, 1, len(m.GetByIndexRange("Amount", "00001000", "99999999")))
+	assert.Equal(t, 0, len(m.GetByIndexRange("Amount", "00000201", "00000209")))
+
+	assert.Equal(t, 30, len(m.GetByIndexRange("Status", "S01", "S03")))
+	assert.Equal(t, 0, len(m.GetByIndexRange("Nonexistent", "a", "z")))
+}
+
+func TestGetIndexKeysSorted(t *testing.T) {
+	m := NewOrderMap()
+
+	for _, i := range []int{5, 3, 9, 1, 7} {
+		m.PutInt(i, Order{Id: i, Amount: i, Status: fmt.Sprintf("s%d", 10-i)})
+	}
+
+	assert.Equal(t, []string{"00000001", "00000003", "00000005", "00000007", "00000009"}, m.GetIndexKeysSorted("Amount"))
+	assert.Equal(t, []string{"S1", "S3", "S5", "S7", "S9"}, m.GetIndexKeysSorted("Status"))
+
+	s := m.Snapshot()
+	assert.Equal(t, m.GetIndexKeysSorted("Amount"), s.GetIndexKeysSorted("Amount"))
+
+	m.Clear()
+	assert.Equal(t, 0, len(m.GetIndexKeysSorted("Amount")))
+	assert.Equal(t, 0, len(m.GetByIndexRange("Amount", "0", "9")))
+}
+
+func TestGetByIndexPrefix(t *testing.T) {
+	persons := NewIndexedMapWithOptions(map[string]IndexFunc[Person]{
+		"LastName": func(r *Person) string {
+			return r.LastName
+		},
+	}, Options[Person]{
+		MultiIndexes: map[string]IndexFuncMulti[Person]{
+			"Tag": func(r *Person) []string {
+				return r.Tags
+			},
+		},
+		OrderedIndexes: []string{"LastName"},
+	})
+
+	persons.PutInt(1, Person{Id: 1, LastName: "Smith", Tags: []string{"dev", "devops"}})
+	persons.PutInt(2, Person{Id: 2, LastName: "Smithson", Tags: []string{"admin"}})
+	persons.PutInt(3, Person{Id: 3, LastName: "Doe", Tags: []string{"developer"}})
+	persons.PutInt(4, Person{Id: 4, LastName: "Smart"})
+
+	assert.Equal(t, 2, len(persons.GetByIndexPrefix("LastName", "smi")))
+	assert.Equal(t, 3, len(persons.GetByIndexPrefix("LastName", "SM")))
+	assert.Equal(t, 4, len(persons.GetByIndexPrefix("LastName", "")))
+	assert.Equal(t, 0, len(persons.GetByIndexPrefix("LastName", "x")))
+
+	assert.Equal(t, 2, len(persons.GetByIndexPrefix("Tag", "dev")))
+	assert.Equal(t, 0, len(persons.GetByIndexPrefix("Nonexistent", "dev")))
+}
+
+func TestRemoveAllOrdered(t *testing.T) {
+	m := NewOrderMap()
+
+	for i := range 10 {
+		m.PutInt(i, Order{Id: i, Amount: i * 10})
+	}
+
+	assert.Equal(t, 2, m.RemoveAll([]string{"0", "1"}))
+	assert.Equal(t, 8, len(m.GetIndexKeysSorted("Amount")))
+	assert.Equal(t, "00000020", m.GetIndexKeysSorted("Amount")[0])
+	assert.Equal(t, 0, len(m.GetByIndexRange("Amount", "00000000", "00000010")))
+}
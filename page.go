@@ -0,0 +1,64 @@
+package indexedmap
+
+import (
+	"cmp"
+	"slices"
+)
+
+// Get page of primary keys sorted in ascending order.
+// Keys are materialized and sorted on every call, so pages are stable as long as the map isn't changed.
+// Returns empty slice when offset is past the end or limit isn't positive.
+func (r *IndexedMap[T]) KeysPage(offset, limit int) []string {
+	keys := r.Keys()
+	slices.Sort(keys)
+	return page(keys, offset, limit)
+}
+
+// Get page of elements sorted by primary key in ascending order, see KeysPage.
+func (r *IndexedMap[T]) ValuesPage(offset, limit int) []T {
+	entries := r.Entries()
+	slices.SortFunc(entries, func(a, b Entry[T]) int {
+		return cmp.Compare(a.Key, b.Key)
+	})
+	result := []T{}
+	for _, e := range page(entries, offset, limit) {
+		result = append(result, e.Value)
+	}
+	return result
+}
+
+// Get page of elements having index value, sorted by primary key in ascending order.
+// Keys of the value are materialized and sorted on every call, while only elements of the page are read,
+// so pages are stable as long as elements with this value aren't added or removed.
+// Elements removed concurrently are skipped, so the page may be shorter than limit.
+func (r *IndexedMap[T]) GetByIndexPage(name, v string, offset, limit int) []T {
+	t := r.mu.RLock()
+	defer r.mu.RUnlock(t)
+	result := []T{}
+	m, ok := r.findIndexMapList(name, r.normalize(v))
+	if !ok {
+		return result
+	}
+	keys := make([]string, 0, m.Size())
+	m.Range(func(k string, _ any) bool {
+		keys = append(keys, k)
+		return true
+	})
+	slices.Sort(keys)
+	for _, k := range page(keys, offset, limit) {
+		if obj, ok := r.lookup(k); ok {
+			result = append(result, obj)
+		}
+	}
+	return result
+}
+
+// Cut [offset, offset+limit) part of s, clamped to its bounds.
+func page[E any](s []E, offset, limit int) []E {
+	if offset < 0 || limit <= 0 || offset >= len(s) {
+		return []E{}
+	}
+	// offset+limit overflows for limit close to math.MaxInt, so limit is clamped first
+	limit = min(limit, len(s)-offset)
+	return s[offset : offset+limit]
+}
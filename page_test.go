@@ -0,0 +1,52 @@
+package indexedmap
+
+import (
+	"fmt"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKeysPage(t *testing.T) {
+	m := NewAnimalMap()
+
+	for i := range 25 {
+		m.Put(fmt.Sprintf("a%02d", i), Animal{Id: i, Type: "small"})
+	}
+
+	assert.Equal(t, []string{"a00", "a01", "a02"}, m.KeysPage(0, 3))
+	assert.Equal(t, []string{"a23", "a24"}, m.KeysPage(23, 10))
+	assert.Equal(t, 0, len(m.KeysPage(25, 10)))
+	assert.Equal(t, 0, len(m.KeysPage(0, 0)))
+	assert.Equal(t, 24, len(m.KeysPage(1, math.MaxInt)))
+	assert.Equal(t, 0, len(m.KeysPage(math.MaxInt, math.MaxInt)))
+
+	list := m.ValuesPage(10, 5)
+	assert.Equal(t, 5, len(list))
+	for i, a := range list {
+		assert.Equal(t, 10+i, a.Id)
+	}
+	assert.NotNil(t, m.ValuesPage(-1, 5))
+}
+
+func TestGetByIndexPage(t *testing.T) {
+	m := NewAnimalMap()
+
+	for i := range 25 {
+		typ := "small"
+		if i%2 == 1 {
+			typ = "big"
+		}
+		m.Put(fmt.Sprintf("a%02d", i), Animal{Id: i, Type: typ})
+	}
+
+	assert.Equal(t, []int{0, 2, 4}, animalIds(m.GetByIndexPage("Type", "small", 0, 3)))
+	assert.Equal(t, []int{21, 23}, animalIds(m.GetByIndexPage("Type", "Big", 10, 5)))
+	assert.Equal(t, 0, len(m.GetByIndexPage("Type", "small", 13, 5)))
+	assert.Equal(t, 12, len(m.GetByIndexPage("Type", "small", 1, math.MaxInt)))
+
+	assert.Equal(t, []Animal{}, m.GetByIndexPage("Type", "huge", 0, 5))
+	assert.Equal(t, 2, len(m.GetIndexKeys("Type")))
+	assert.Equal(t, []Animal{}, m.GetByIndexPage("Color", "red", 0, 5))
+}
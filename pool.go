@@ -0,0 +1,75 @@
+package indexedmap
+
+import (
+	"errors"
+	"runtime"
+	"sync"
+)
+
+// Used as panic value by parallel operations given parallelism which is not positive.
+var ErrInvalidParallelism = errors.New("indexedmap: parallelism must be positive")
+
+// Limit of goroutines running batches of parallel operations (PutAll, RemoveAll, GetByIndexParallel and others),
+// shared by all maps using it. Batches which don't get a free worker run in the calling goroutine,
+// so repeated or concurrent bulk calls never spawn more goroutines than the pool size,
+// and operations never wait for each other to release workers.
+type WorkerPool struct {
+	slots chan struct{}
+}
+
+// Pool used by maps without WorkerPool option, sized to the number of CPUs.
+var defaultPool = NewWorkerPool(runtime.NumCPU())
+
+// Create pool running at most workers goroutines at once, at least one.
+func NewWorkerPool(workers int) *WorkerPool {
+	return &WorkerPool{slots: make(chan struct{}, max(workers, 1))}
+}
+
+// Limit parallelism of operation over count elements to one goroutine per element,
+// so batches are never empty. Panics with ErrInvalidParallelism if parallelism is not positive.
+func threads(count int, parallelism int) int {
+	checkParallelism(parallelism)
+	return max(min(parallelism, count), 1)
+}
+
+func checkParallelism(parallelism int) {
+	if parallelism <= 0 {
+		panic(ErrInvalidParallelism)
+	}
+}
+
+// Get worker pool of the map.
+func (r *IndexedMap[T]) pool() *WorkerPool {
+	if r.opts.WorkerPool != nil {
+		return r.opts.WorkerPool
+	}
+	return defaultPool
+}
+
+// Split [0, count) range into threads batches and process them by fn in parallel.
+// The first batch and batches not getting a free worker run in the calling goroutine.
+// Waits until all batches are done.
+func (p *WorkerPool) partition(count int, threads int, fn func(part, lo, hi int)) {
+	threads = max(threads, 1)
+	// round batch size up, so threads cover all elements
+	batch := (count + threads - 1) / threads
+	var wg sync.WaitGroup
+	for i := 1; i < threads; i++ {
+		lo, hi := min(i*batch, count), min((i+1)*batch, count)
+		select {
+		case p.slots <- struct{}{}:
+			wg.Add(1)
+			go func() {
+				defer func() {
+					<-p.slots
+					wg.Done()
+				}()
+				fn(i, lo, hi)
+			}()
+		default:
+			fn(i, lo, hi)
+		}
+	}
+	fn(0, 0, min(batch, count))
+	wg.Wait()
+}
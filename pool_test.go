@@ -0,0 +1,85 @@
+package indexedmap
+
+import (
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWorkerPool(t *testing.T) {
+	p := NewWorkerPool(2)
+
+	var running atomic.Int32
+	peak := int32(0)
+	var mu sync.Mutex
+	covered := make([]int, 1000)
+	var wg sync.WaitGroup
+	for range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			p.partition(len(covered), 8, func(part, lo, hi int) {
+				n := running.Add(1)
+				defer running.Add(-1)
+				mu.Lock()
+				defer mu.Unlock()
+				peak = max(peak, n)
+				for i := lo; i < hi; i++ {
+					covered[i]++
+				}
+			})
+		}()
+	}
+	wg.Wait()
+
+	// 4 callers plus 2 workers
+	assert.LessOrEqual(t, peak, int32(6))
+	for _, c := range covered {
+		assert.Equal(t, 4, c)
+	}
+	assert.Equal(t, 0, len(p.slots))
+
+	calls := 0
+	NewWorkerPool(0).partition(0, 0, func(part, lo, hi int) {
+		calls++
+		assert.Equal(t, lo, hi)
+	})
+	assert.Equal(t, 1, calls)
+}
+
+func TestSharedWorkerPool(t *testing.T) {
+	pool := NewWorkerPool(2)
+	opts := Options[Animal]{WorkerPool: pool}
+	indexes := map[string]IndexFunc[Animal]{
+		"Type": func(a *Animal) string {
+			return a.Type
+		},
+	}
+	a := NewIndexedMapWithOptions(indexes, opts)
+	b := NewIndexedMapWithOptions(indexes, opts)
+
+	data := make([]Animal, 0, 20000)
+	for i := range cap(data) {
+		data = append(data, Animal{Id: i, Type: "t" + strconv.Itoa(i%4)})
+	}
+	keyFunc := func(a *Animal) string { return strconv.Itoa(a.Id) }
+	var wg sync.WaitGroup
+	for _, m := range []*IndexedMap[Animal]{a, b} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			m.PutAllWith(data, keyFunc, 16)
+		}()
+	}
+	wg.Wait()
+
+	assert.Same(t, pool, a.pool())
+	assert.Same(t, defaultPool, NewAnimalMap().pool())
+	assert.Equal(t, len(data), a.Size())
+	assert.Equal(t, len(data), b.Size())
+	assert.Equal(t, 5000, len(b.GetByIndexParallelWith("Type", "t1", 16)))
+	assert.Empty(t, a.GetByIndexBatchWith(nil, 4))
+}
@@ -0,0 +1,26 @@
+package indexedmap
+
+import "strconv"
+
+// Create index function putting elements to buckets of numbers by width, like a histogram.
+// Bucket labels are "lo-hi" with inclusive bounds, e.g. "0-9" and "10-19" for width 10,
+// see RangeLabel to get label for a number. Buckets of negative numbers are aligned the same way,
+// e.g. -1 goes to "-10--1". Panics if width is not positive.
+func RangeIndexFunc[T any](extract func(*T) int, width int) IndexFunc[T] {
+	if width <= 0 {
+		panic("indexedmap: range index width must be positive")
+	}
+	return func(obj *T) string {
+		return RangeLabel(extract(obj), width)
+	}
+}
+
+// Get label of bucket of width containing v, as produced by RangeIndexFunc.
+func RangeLabel(v int, width int) string {
+	lo := v / width * width
+	if v < 0 && lo != v {
+		// integer division rounds toward zero, buckets of negative numbers need floor
+		lo -= width
+	}
+	return strconv.Itoa(lo) + "-" + strconv.Itoa(lo+width-1)
+}
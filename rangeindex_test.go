@@ -0,0 +1,43 @@
+package indexedmap
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRangeLabel(t *testing.T) {
+	tests := []struct {
+		v     int
+		width int
+		label string
+	}{
+		{0, 10, "0-9"},
+		{9, 10, "0-9"},
+		{10, 10, "10-19"},
+		{-1, 10, "-10--1"},
+		{-10, 10, "-10--1"},
+		{-11, 10, "-20--11"},
+		{7, 1, "7-7"},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.label, RangeLabel(tt.v, tt.width), "%d by %d", tt.v, tt.width)
+	}
+	assert.Panics(t, func() { RangeIndexFunc(func(a *Animal) int { return a.NumType }, 0) })
+}
+
+func TestRangeIndexFunc(t *testing.T) {
+	m := NewIndexedMap(map[string]IndexFunc[Animal]{
+		"NumTypeRange": RangeIndexFunc(func(a *Animal) int { return a.NumType }, 10),
+	})
+
+	m.PutInt(1, Animal{Id: 1, NumType: 3})
+	m.PutInt(2, Animal{Id: 2, NumType: 9})
+	m.PutInt(3, Animal{Id: 3, NumType: 12})
+	m.PutInt(4, Animal{Id: 4, NumType: -4})
+
+	assert.Equal(t, 2, len(m.GetByIndex("NumTypeRange", "0-9")))
+	assert.Equal(t, 1, len(m.GetByIndex("NumTypeRange", RangeLabel(15, 10))))
+	assert.Equal(t, 1, len(m.GetByIndex("NumTypeRange", "-10--1")))
+	assert.ElementsMatch(t, []string{"0-9", "10-19", "-10--1"}, m.GetIndexKeys("NumTypeRange"))
+}
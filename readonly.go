@@ -0,0 +1,74 @@
+package indexedmap
+
+// Read-only view of IndexedMap, see IndexedMap.ReadOnly.
+type ReadOnlyIndexedMap[T any] interface {
+	Get(key string) (T, bool)
+	GetInt(key int) (T, bool)
+	ContainsKey(key string) bool
+	GetByIndex(name string, v string) []T
+	GetIndexKeys(name string) []string
+	Keys() []string
+	Values() []T
+	Size() int
+	IsEmpty() bool
+	ForEach(fn func(key string, value T) bool)
+	RangeByIndex(name string, v string, fn func(T) bool)
+
+	// All and ByIndex iterators, only with Go 1.23 and later like IndexedMap ones
+	readOnlyIterators[T]
+}
+
+// Get view of the map exposing only read methods, e.g. to pass it to code which must not change it.
+// View shares data with the map, so it's cheap to create and observes later changes of the map.
+// Write methods are hidden by the view type and can't be reached by type assertion.
+func (r *IndexedMap[T]) ReadOnly() ReadOnlyIndexedMap[T] {
+	return readOnlyMap[T]{r: r}
+}
+
+type readOnlyMap[T any] struct {
+	r *IndexedMap[T]
+}
+
+func (v readOnlyMap[T]) Get(key string) (T, bool) {
+	return v.r.Get(key)
+}
+
+func (v readOnlyMap[T]) GetInt(key int) (T, bool) {
+	return v.r.GetInt(key)
+}
+
+func (v readOnlyMap[T]) ContainsKey(key string) bool {
+	return v.r.ContainsKey(key)
+}
+
+func (v readOnlyMap[T]) GetByIndex(name string, value string) []T {
+	return v.r.GetByIndex(name, value)
+}
+
+func (v readOnlyMap[T]) GetIndexKeys(name string) []string {
+	return v.r.GetIndexKeys(name)
+}
+
+func (v readOnlyMap[T]) Keys() []string {
+	return v.r.Keys()
+}
+
+func (v readOnlyMap[T]) Values() []T {
+	return v.r.Values()
+}
+
+func (v readOnlyMap[T]) Size() int {
+	return v.r.Size()
+}
+
+func (v readOnlyMap[T]) IsEmpty() bool {
+	return v.r.IsEmpty()
+}
+
+func (v readOnlyMap[T]) ForEach(fn func(key string, value T) bool) {
+	v.r.ForEach(fn)
+}
+
+func (v readOnlyMap[T]) RangeByIndex(name string, value string, fn func(T) bool) {
+	v.r.RangeByIndex(name, value, fn)
+}
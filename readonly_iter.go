@@ -0,0 +1,19 @@
+//go:build go1.23
+
+package indexedmap
+
+import "iter"
+
+// Iterators of ReadOnlyIndexedMap, see IndexedMap.All and IndexedMap.ByIndex.
+type readOnlyIterators[T any] interface {
+	All() iter.Seq2[string, T]
+	ByIndex(name string, v string) iter.Seq[T]
+}
+
+func (v readOnlyMap[T]) All() iter.Seq2[string, T] {
+	return v.r.All()
+}
+
+func (v readOnlyMap[T]) ByIndex(name string, value string) iter.Seq[T] {
+	return v.r.ByIndex(name, value)
+}
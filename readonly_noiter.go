@@ -0,0 +1,6 @@
+//go:build !go1.23
+
+package indexedmap
+
+// Iterators need Go 1.23, so older toolchains get ReadOnlyIndexedMap without them.
+type readOnlyIterators[T any] interface{}
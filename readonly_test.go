@@ -0,0 +1,26 @@
+package indexedmap
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadOnly(t *testing.T) {
+	m := NewAnimalMap()
+	m.PutInt(1, Animal{Id: 1, Name: "Cat", Type: "small"})
+
+	v := m.ReadOnly()
+	m.PutInt(2, Animal{Id: 2, Name: "Cow", Type: "big"})
+
+	assert.Equal(t, 2, v.Size())
+	a, ok := v.GetInt(2)
+	assert.True(t, ok)
+	assert.Equal(t, "Cow", a.Name)
+	assert.Equal(t, 1, len(v.GetByIndex("Type", "small")))
+	assert.Equal(t, 2, len(v.Values()))
+	assert.ElementsMatch(t, []string{"1", "2"}, v.Keys())
+
+	_, ok = v.(interface{ Put(string, Animal) })
+	assert.False(t, ok)
+}
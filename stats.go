@@ -0,0 +1,133 @@
+package indexedmap
+
+import "github.com/puzpuzpuz/xsync/v3"
+
+// Distribution of elements among values of secondary index.
+type IndexStats struct {
+	// Number of distinct index values having elements
+	Values int
+
+	// Smallest and largest number of elements per value
+	Min int
+	Max int
+
+	// Value having the largest number of elements, the first one found on tie
+	MaxValue string
+
+	// Average number of elements per value
+	Mean float64
+}
+
+// Compute distribution statistics of index in a single pass over its values.
+// Concurrent writes are tolerated, so the result is approximate while the map changes.
+// Values without elements are skipped, unknown index yields zero stats. Expired elements are not counted.
+func (r *IndexedMap[T]) IndexStats(name string) IndexStats {
+	t := r.mu.RLock()
+	defer r.mu.RUnlock(t)
+	return r.indexStats(name)
+}
+
+func (r *IndexedMap[T]) indexStats(name string) IndexStats {
+	stats := IndexStats{}
+	index, ok := r.secondary[name]
+	if !ok {
+		return stats
+	}
+	total := 0
+	index.Range(func(k string, v any) bool {
+		size := r.countLive(v.(*xsync.Map))
+		if size == 0 {
+			return true
+		}
+		if stats.Values == 0 || size < stats.Min {
+			stats.Min = size
+		}
+		if size > stats.Max {
+			stats.Max = size
+			stats.MaxValue = k
+		}
+		stats.Values++
+		total += size
+		return true
+	})
+	if stats.Values > 0 {
+		stats.Mean = float64(total) / float64(stats.Values)
+	}
+	return stats
+}
+
+// Count elements by each value of index in a single pass.
+// Values without elements are omitted, unknown index yields empty map. Expired elements are not counted.
+func (r *IndexedMap[T]) IndexValueCounts(name string) map[string]int {
+	t := r.mu.RLock()
+	defer r.mu.RUnlock(t)
+	result := map[string]int{}
+	index, ok := r.secondary[name]
+	if !ok {
+		return result
+	}
+	index.Range(func(k string, v any) bool {
+		if size := r.countLive(v.(*xsync.Map)); size > 0 {
+			result[k] = size
+		}
+		return true
+	})
+	return result
+}
+
+// Count elements present in index and all elements of the map, e.g. to find index functions
+// returning empty values more often than expected. Elements of index are counted by summing
+// sizes of its value collections, so element of multi-value index is counted once per value.
+// Expired elements are not counted, like in Size. Unknown index has no indexed elements.
+func (r *IndexedMap[T]) CountIndexed(name string) (indexed, total int) {
+	t := r.mu.RLock()
+	defer r.mu.RUnlock(t)
+	if index, ok := r.secondary[name]; ok {
+		index.Range(func(k string, v any) bool {
+			indexed += r.countLive(v.(*xsync.Map))
+			return true
+		})
+	}
+	return indexed, r.Size()
+}
+
+// Plain numbers describing the map for metrics exporters.
+type Metrics struct {
+	// Number of elements
+	Size int
+
+	// Number of elements put or updated, and removed since the map was created
+	Puts    uint64
+	Removes uint64
+
+	// Number of distinct values by index name
+	IndexValues map[string]int
+}
+
+// Collect current metrics of the map.
+// Counters are read without stopping writers, so they may be slightly off relative to each other.
+func (r *IndexedMap[T]) Metrics() Metrics {
+	t := r.mu.RLock()
+	defer r.mu.RUnlock(t)
+	m := Metrics{
+		Size:        r.Size(),
+		Puts:        r.puts.Load(),
+		Removes:     r.removes.Load(),
+		IndexValues: make(map[string]int, len(r.indexes)),
+	}
+	for name := range r.indexes {
+		m.IndexValues[name] = r.indexStats(name).Values
+	}
+	return m
+}
+
+// Get function collecting metrics on every call, e.g. for publishing them with expvar:
+//
+//	expvar.Publish("animals", expvar.Func(m.MetricsFunc()))
+//
+// Package doesn't import expvar itself, so it doesn't register its HTTP handler.
+func (r *IndexedMap[T]) MetricsFunc() func() any {
+	return func() any {
+		return r.Metrics()
+	}
+}
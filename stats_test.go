@@ -0,0 +1,110 @@
+package indexedmap
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIndexStats(t *testing.T) {
+	m := NewAnimalMap()
+
+	for i := range 10 {
+		typ := "big"
+		if i == 0 {
+			typ = "small"
+		}
+		m.PutInt(i, Animal{Id: i, Name: "animal" + strconv.Itoa(i), Type: typ})
+	}
+
+	stats := m.IndexStats("Type")
+	assert.Equal(t, 2, stats.Values)
+	assert.Equal(t, 1, stats.Min)
+	assert.Equal(t, 9, stats.Max)
+	assert.Equal(t, "BIG", stats.MaxValue)
+	assert.Equal(t, 5.0, stats.Mean)
+
+	m.RemoveInt(0)
+	stats = m.IndexStats("Type")
+	assert.Equal(t, 1, stats.Values)
+	assert.Equal(t, 9, stats.Min)
+
+	m.PutWithTTL("10", Animal{Id: 10, Type: "tiny"}, time.Millisecond)
+	time.Sleep(5 * time.Millisecond)
+	assert.Equal(t, 1, m.IndexStats("Type").Values)
+	assert.Equal(t, 1, m.Metrics().IndexValues["Type"])
+
+	assert.Equal(t, IndexStats{}, m.IndexStats("Color"))
+}
+
+func TestMetrics(t *testing.T) {
+	m := NewAnimalMap()
+
+	for i := range 10 {
+		m.PutInt(i, Animal{Id: i, Name: "animal" + strconv.Itoa(i%5), Type: "small"})
+	}
+	m.PutInt(0, Animal{Id: 0, Name: "animal0", Type: "big"})
+	m.RemoveInt(1)
+	m.RemoveInt(100)
+
+	metrics := m.Metrics()
+	assert.Equal(t, 9, metrics.Size)
+	assert.Equal(t, uint64(11), metrics.Puts)
+	assert.Equal(t, uint64(1), metrics.Removes)
+	assert.Equal(t, 4, len(metrics.IndexValues))
+	assert.Equal(t, 2, metrics.IndexValues["Type"])
+	assert.Equal(t, 0, metrics.IndexValues["Role"])
+
+	assert.Equal(t, metrics, m.MetricsFunc()())
+}
+
+func TestIndexValueCounts(t *testing.T) {
+	m := NewAnimalMap()
+
+	for i := range 10 {
+		m.PutInt(i, Animal{Id: i, Name: "animal" + strconv.Itoa(i), Type: "t" + strconv.Itoa(i%3)})
+	}
+	m.RemoveInt(0)
+	m.RemoveInt(3)
+	m.RemoveInt(6)
+	m.RemoveInt(9)
+
+	assert.Equal(t, map[string]int{"T1": 3, "T2": 3}, m.IndexValueCounts("Type"))
+	assert.Equal(t, map[string]int{}, m.IndexValueCounts("Color"))
+
+	m.PutWithTTL("0", Animal{Id: 0, Type: "t0"}, time.Millisecond)
+	m.PutWithTTL("1", Animal{Id: 1, Type: "t1"}, time.Millisecond)
+	time.Sleep(5 * time.Millisecond)
+	assert.Equal(t, map[string]int{"T1": 2, "T2": 3}, m.IndexValueCounts("Type"))
+}
+
+func TestCountIndexed(t *testing.T) {
+	m := NewAnimalMap()
+
+	for i := range 10 {
+		typ := ""
+		if i%4 == 0 {
+			typ = "small"
+		}
+		m.PutInt(i, Animal{Id: i, Type: typ})
+	}
+
+	indexed, total := m.CountIndexed("Type")
+	assert.Equal(t, 3, indexed)
+	assert.Equal(t, 10, total)
+	assert.Equal(t, total-indexed, len(m.GetUnindexed("Type")))
+
+	indexed, total = m.CountIndexed("Color")
+	assert.Equal(t, 0, indexed)
+	assert.Equal(t, 10, total)
+
+	m.PutWithTTL("0", Animal{Id: 0, Type: "small"}, time.Millisecond)
+	m.PutWithTTL("1", Animal{Id: 1}, time.Millisecond)
+	time.Sleep(5 * time.Millisecond)
+	indexed, total = m.CountIndexed("Type")
+	assert.Equal(t, 2, indexed)
+	assert.Equal(t, 8, total)
+	assert.Equal(t, total-indexed, len(m.GetUnindexed("Type")))
+}
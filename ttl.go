@@ -0,0 +1,164 @@
+package indexedmap
+
+import (
+	"time"
+)
+
+// Add element to map by primary key with time to live.
+// Expired element is treated as absent: it's not returned by lookups, iteration and Size,
+// and writers of its key remove it first, reporting OpDelete to listeners like RemoveExpired.
+// Until then it's kept in the map and its secondary indexes, see also StartSweeper.
+// Regular Put of the same key before expiration makes element permanent again.
+func (r *IndexedMap[T]) PutWithTTL(k string, obj T, ttl time.Duration) {
+	r.checkOpen()
+	if r.expiry.Load() == nil {
+		r.expiry.CompareAndSwap(nil, newExpiryIndex())
+	}
+	key := r.normalize(k)
+	var prev T
+	var loaded bool
+	var dropped []ChangeEvent[T]
+	var err error
+	r.withRLock(func() {
+		prev, loaded, dropped, err = r.putExpiring(key, k, obj, time.Now().Add(ttl).UnixNano())
+	})
+	r.notify(dropped...)
+	if err == nil {
+		r.notifyPut(key, prev, obj, loaded)
+		r.evict()
+	}
+}
+
+// Remove all expired elements from primary and secondary indexes.
+// Returns number of removed elements.
+func (r *IndexedMap[T]) RemoveExpired() int {
+	r.checkOpen()
+	e := r.expiry.Load()
+	if e == nil {
+		return 0
+	}
+	now := time.Now().UnixNano()
+	removed := []ChangeEvent[T]{}
+	r.withRLock(func() {
+		e.entries.Range(func(k string, v any) bool {
+			if v.(*expiryEntry).deadline > now {
+				return true
+			}
+			// element could be put again since deadline was read, compute drops it only if still expired
+			removed = append(removed, r.compute(k, func(old any, loaded bool) (any, bool) {
+				return old, !loaded
+			})...)
+			return true
+		})
+	})
+	r.notify(removed...)
+	return len(removed)
+}
+
+// Start background goroutine calling RemoveExpired every interval.
+// Does nothing if sweeper is already running or map is closed. Stop it with StopSweeper or Close.
+func (r *IndexedMap[T]) StartSweeper(interval time.Duration) {
+	r.sweeperMu.Lock()
+	defer r.sweeperMu.Unlock()
+	if r.stopSweeper != nil || r.closed.Load() {
+		return
+	}
+	stop, done := make(chan struct{}), make(chan struct{})
+	r.stopSweeper, r.sweeperDone = stop, done
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				r.RemoveExpired()
+			case <-stop:
+				return
+			}
+		}
+	}()
+}
+
+// Stop background sweeper and wait for it to exit.
+// Does nothing if sweeper is not running.
+func (r *IndexedMap[T]) StopSweeper() {
+	r.sweeperMu.Lock()
+	defer r.sweeperMu.Unlock()
+	r.stopSweeperLocked()
+}
+
+func (r *IndexedMap[T]) stopSweeperLocked() {
+	if r.stopSweeper == nil {
+		return
+	}
+	close(r.stopSweeper)
+	<-r.sweeperDone
+	r.stopSweeper, r.sweeperDone = nil, nil
+}
+
+// Stop background goroutines like sweeper and make the map read-only.
+// Reads keep working on closed map, writes and index changes panic with ErrClosed,
+// while PutErr, RemoveErr, Transaction and loaders like ReadJSON return it. Writes check it before taking any lock,
+// so the map stays usable for reads after such panic is recovered.
+// Writes running concurrently with Close may still complete. Closing already closed map does nothing.
+func (r *IndexedMap[T]) Close() error {
+	r.sweeperMu.Lock()
+	defer r.sweeperMu.Unlock()
+	r.stopSweeperLocked()
+	r.closed.Store(true)
+	return nil
+}
+
+// Change primary index element atomically, see xsync.Map.Compute.
+// Fn gets current element and whether it's present, and returns new element and true to delete it.
+// Expired element is removed from secondary indexes first and fn gets its key as absent,
+// as if RemoveExpired ran just before. Returns OpDelete event of such element, nil otherwise,
+// to be delivered by the caller once locks are released.
+func (r *IndexedMap[T]) compute(key string, fn func(old any, loaded bool) (any, bool)) []ChangeEvent[T] {
+	var dropped []ChangeEvent[T]
+	r.primary.Compute(key, func(old any, loaded bool) (any, bool) {
+		if loaded && r.expired(key) {
+			dropped = []ChangeEvent[T]{{Op: OpDelete, Key: key, Old: r.unbox(old)}}
+			r.unindex(key)
+			old, loaded = nil, false
+		}
+		return fn(old, loaded)
+	})
+	return dropped
+}
+
+// Panic with ErrClosed if the map is closed, called by writers before taking any lock.
+func (r *IndexedMap[T]) checkOpen() {
+	if r.closed.Load() {
+		panic(ErrClosed)
+	}
+}
+
+// Set or reset expiration deadline of element, must be called under primary key lock.
+func (r *IndexedMap[T]) setExpiry(key string, deadline int64) {
+	if e := r.expiry.Load(); e != nil {
+		e.set(key, deadline)
+	}
+}
+
+// Get expiration deadline of element, 0 if it never expires.
+func (r *IndexedMap[T]) deadline(key string) int64 {
+	if e := r.expiry.Load(); e != nil {
+		return e.deadline(key)
+	}
+	return 0
+}
+
+// Count elements which expired but are not removed yet, see expiryIndex.
+func (r *IndexedMap[T]) expiredCount() int {
+	if e := r.expiry.Load(); e != nil {
+		return e.expiredCount(time.Now().UnixNano())
+	}
+	return 0
+}
+
+func (r *IndexedMap[T]) expired(key string) bool {
+	d := r.deadline(key)
+	return d > 0 && d <= time.Now().UnixNano()
+}
@@ -0,0 +1,266 @@
+package indexedmap
+
+import (
+	"bytes"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPutWithTTL(t *testing.T) {
+	m := NewAnimalMap()
+
+	m.PutWithTTL("1", Animal{Id: 1, Name: "Cat", Type: "small"}, time.Millisecond)
+	m.PutWithTTL("2", Animal{Id: 2, Name: "Dog", Type: "small"}, time.Hour)
+	m.PutWithTTL("3", Animal{Id: 3, Name: "Cow", Type: "big"}, time.Millisecond)
+	m.PutInt(3, Animal{Id: 3, Name: "Cow", Type: "big"})
+
+	time.Sleep(5 * time.Millisecond)
+
+	_, ok := m.Get("1")
+	assert.False(t, ok)
+	assert.True(t, m.ContainsKey("2"))
+	assert.True(t, m.ContainsKey("3"))
+
+	assert.Equal(t, 1, m.RemoveExpired())
+	assert.Equal(t, 2, m.Size())
+	assert.Equal(t, 1, len(m.GetByIndex("Type", "small")))
+	assert.Equal(t, 0, m.RemoveExpired())
+}
+
+func TestSweeper(t *testing.T) {
+	m := NewAnimalMap()
+
+	m.StartSweeper(time.Millisecond)
+	m.StartSweeper(time.Millisecond)
+	defer m.Close()
+
+	m.PutWithTTL("1", Animal{Id: 1, Name: "Cat", Type: "small"}, time.Millisecond)
+	m.PutInt(2, Animal{Id: 2, Name: "Dog", Type: "small"})
+
+	assert.Eventually(t, func() bool {
+		return m.Size() == 1
+	}, time.Second, time.Millisecond)
+	assert.Equal(t, 1, len(m.GetByIndex("Type", "small")))
+
+	assert.NoError(t, m.Close())
+	m.StopSweeper()
+}
+
+func TestClose(t *testing.T) {
+	m := NewAnimalMap()
+	m.PutInt(1, Animal{Id: 1, Name: "Cat", Type: "small"})
+	m.StartSweeper(time.Millisecond)
+
+	assert.NoError(t, m.Close())
+	assert.NoError(t, m.Close())
+	m.StartSweeper(time.Millisecond)
+	assert.Nil(t, m.stopSweeper)
+
+	o, ok := m.GetInt(1)
+	assert.True(t, ok)
+	assert.Equal(t, "Cat", o.Name)
+	assert.Equal(t, 1, len(m.GetByIndex("Type", "small")))
+
+	assert.ErrorIs(t, m.PutErr("2", Animal{Id: 2, Name: "Dog", Type: "small"}), ErrClosed)
+	assert.PanicsWithValue(t, ErrClosed, func() { m.PutInt(2, Animal{Id: 2, Name: "Dog", Type: "small"}) })
+	assert.PanicsWithValue(t, ErrClosed, func() { m.RemoveInt(1) })
+	assert.PanicsWithValue(t, ErrClosed, func() { m.Clear() })
+	assert.PanicsWithValue(t, ErrClosed, func() { m.PutIfAbsent("2", Animal{Id: 2, Name: "Dog", Type: "small"}) })
+	_, _, err := m.PutIfAbsentErr("2", Animal{Id: 2, Name: "Dog", Type: "small"})
+	assert.ErrorIs(t, err, ErrClosed)
+	assert.PanicsWithValue(t, ErrClosed, func() { m.Update("1", func(*Animal) bool { return true }) })
+	assert.PanicsWithValue(t, ErrClosed, func() { m.RemoveByIndex("Type", "small") })
+	assert.PanicsWithValue(t, ErrClosed, func() { m.RemoveExpired() })
+	assert.ErrorIs(t, m.Transaction(func(tx *Tx[Animal]) error {
+		tx.Remove("1")
+		return nil
+	}), ErrClosed)
+
+	// bulk writes are rejected before workers start
+	data := make([]Animal, 0, 10000)
+	keys := make([]string, 0, cap(data))
+	for i := range cap(data) {
+		data = append(data, Animal{Id: i, Name: "Rat", Type: "small"})
+		keys = append(keys, strconv.Itoa(i))
+	}
+	assert.PanicsWithValue(t, ErrClosed, func() {
+		m.PutAllWith(data, func(a *Animal) string { return strconv.Itoa(a.Id) }, 2)
+	})
+	assert.PanicsWithValue(t, ErrClosed, func() { m.RemoveAllWith(keys, 2) })
+
+	// index changes are writes too
+	assert.PanicsWithValue(t, ErrClosed, func() { m.Reindex() })
+	assert.PanicsWithValue(t, ErrClosed, func() { m.AddIndex("Name", func(a *Animal) string { return a.Name }) })
+	assert.PanicsWithValue(t, ErrClosed, func() { m.SetIndexFunc("Type", func(a *Animal) string { return a.Name }) })
+	assert.PanicsWithValue(t, ErrClosed, func() { m.RemoveIndex("Type") })
+	assert.False(t, m.HasIndex("Name"))
+	assert.True(t, m.HasIndex("Type"))
+
+	// loaders return the error instead
+	assert.ErrorIs(t, json.Unmarshal([]byte(`{"2":{"Id":2}}`), m), ErrClosed)
+	assert.ErrorIs(t, m.ReadJSON(strings.NewReader(`{"2":{"Id":2}}`)), ErrClosed)
+	assert.ErrorIs(t, m.ReadCSV(strings.NewReader("2,Dog\n"), false, 0, func(row []string) (Animal, error) {
+		return Animal{Name: row[1]}, nil
+	}), ErrClosed)
+	var buf bytes.Buffer
+	assert.NoError(t, NewAnimalMap().Save(&buf))
+	assert.ErrorIs(t, m.Load(&buf), ErrClosed)
+	assert.False(t, m.ContainsKeyInt(2))
+
+	// rejected writes don't hold map lock
+	m.mu.Lock()
+	m.mu.Unlock()
+	assert.Equal(t, 1, len(m.GetByIndex("Type", "small")))
+	assert.Equal(t, 1, m.Size())
+}
+
+func TestExpiredIsAbsent(t *testing.T) {
+	m := NewAnimalMap()
+	events := []ChangeEvent[Animal]{}
+	m.OnChange(func(e ChangeEvent[Animal]) {
+		events = append(events, e)
+	})
+
+	cats := []Animal{}
+	for i := 1; i <= 4; i++ {
+		cats = append(cats, Animal{Id: i, Name: "Cat" + strconv.Itoa(i), Type: "small"})
+		m.PutWithTTL(strconv.Itoa(i), cats[i-1], time.Millisecond)
+	}
+	m.PutInt(5, Animal{Id: 5, Name: "Dog", Type: "big"})
+	time.Sleep(5 * time.Millisecond)
+
+	assert.Equal(t, 1, m.Size())
+	assert.Equal(t, []string{"5"}, m.Keys())
+	assert.Equal(t, 1, len(m.Values()))
+	n := 0
+	m.ForEach(func(string, Animal) bool {
+		n++
+		return true
+	})
+	assert.Equal(t, 1, n)
+	assert.Empty(t, m.GetByIndex("Type", "small"))
+	assert.Empty(t, m.GetKeysByIndex("Type", "small"))
+	assert.False(t, m.ContainsIndexValue("Type", "small"))
+	assert.Equal(t, 0, m.CountByIndex("Type", "small"))
+
+	events = events[:0]
+	o, loaded := m.PutIfAbsent("1", Animal{Id: 1, Name: "Tom", Type: "small"})
+	assert.False(t, loaded)
+	assert.Equal(t, "Tom", o.Name)
+	o, created := m.ComputeIfAbsent("2", func() Animal {
+		return Animal{Id: 2, Name: "Rex", Type: "big"}
+	})
+	assert.True(t, created)
+	assert.Equal(t, "Rex", o.Name)
+	assert.False(t, m.Update("3", func(a *Animal) bool {
+		a.Name = "Max"
+		return true
+	}))
+	assert.False(t, m.CompareAndSwap("4", cats[3], Animal{Id: 4, Name: "Max", Type: "small"}, func(a, b Animal) bool {
+		return a == b
+	}))
+	assert.False(t, m.ContainsKey("3"))
+	assert.False(t, m.ContainsKey("4"))
+
+	// writers removed expired elements, reporting them like RemoveExpired does
+	assert.Equal(t, []ChangeEvent[Animal]{
+		{Op: OpDelete, Key: "1", Old: cats[0]},
+		{Op: OpInsert, Key: "1", New: Animal{Id: 1, Name: "Tom", Type: "small"}},
+		{Op: OpDelete, Key: "2", Old: cats[1]},
+		{Op: OpInsert, Key: "2", New: Animal{Id: 2, Name: "Rex", Type: "big"}},
+		{Op: OpDelete, Key: "3", Old: cats[2]},
+		{Op: OpDelete, Key: "4", Old: cats[3]},
+	}, events)
+	assert.Equal(t, 0, m.RemoveExpired())
+	assert.Equal(t, 3, m.Size())
+	assert.Equal(t, 3, m.ApproxSize())
+	assert.Equal(t, 1, len(m.GetByIndex("Type", "small")))
+	assert.Equal(t, 2, len(m.GetByIndex("Type", "big")))
+	assert.Empty(t, m.Validate())
+}
+
+func TestExpiredReleasesUniqueValue(t *testing.T) {
+	m := NewUniquePersonMap()
+	m.PutWithTTL("1", Person{Id: 1, LastName: "John", SSN: "111"}, time.Millisecond)
+	time.Sleep(5 * time.Millisecond)
+
+	assert.NoError(t, m.PutErr("2", Person{Id: 2, LastName: "Doe", SSN: "111"}))
+	assert.Equal(t, 1, m.RemoveExpired())
+	assert.Equal(t, []int{2}, personIds(m.GetByIndex("SSN", "111")))
+	assert.Empty(t, m.Validate())
+}
+
+func TestSizeWithTTL(t *testing.T) {
+	m := NewAnimalMap()
+	for i := range 10 {
+		m.PutWithTTL(strconv.Itoa(i), Animal{Id: i, Name: "Cat", Type: "small"}, time.Millisecond)
+	}
+	m.PutWithTTL("10", Animal{Id: 10, Name: "Dog", Type: "big"}, time.Hour)
+	// overwritten, removed and extended before deadline
+	m.PutInt(0, Animal{Id: 0, Name: "Cat", Type: "small"})
+	m.RemoveInt(1)
+	m.PutWithTTL("2", Animal{Id: 2, Name: "Cat", Type: "small"}, time.Hour)
+	time.Sleep(5 * time.Millisecond)
+
+	assert.Equal(t, 3, m.Size())
+	assert.Equal(t, 10, m.ApproxSize())
+	assert.Equal(t, m.Size(), m.ExactSize())
+	assert.Equal(t, m.Size(), len(m.Keys()))
+
+	// overwrite and removal of expired elements
+	m.PutInt(3, Animal{Id: 3, Name: "Cat", Type: "small"})
+	m.RemoveInt(4)
+	assert.Equal(t, 4, m.Size())
+	assert.Equal(t, m.Size(), m.ExactSize())
+
+	assert.Equal(t, 5, m.RemoveExpired())
+	assert.Equal(t, 4, m.Size())
+	assert.Equal(t, 4, m.ApproxSize())
+
+	m.PutWithTTL("5", Animal{Id: 5, Name: "Cat", Type: "small"}, time.Millisecond)
+	time.Sleep(5 * time.Millisecond)
+	assert.Equal(t, 4, m.Size())
+	m.Clear()
+	assert.Equal(t, 0, m.Size())
+	m.PutWithTTL("5", Animal{Id: 5, Name: "Cat", Type: "small"}, time.Hour)
+	assert.Equal(t, 1, m.Size())
+	assert.Equal(t, 1, m.ExactSize())
+}
+
+func TestSizeWithTTLConcurrent(t *testing.T) {
+	m := NewAnimalMap()
+
+	var wg sync.WaitGroup
+	for w := range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range 3000 {
+				k := strconv.Itoa((i*7 + w) % 100)
+				switch i % 4 {
+				case 0:
+					m.Put(k, Animal{Id: i, Type: "small"})
+				case 1:
+					m.Remove(k)
+				default:
+					m.PutWithTTL(k, Animal{Id: i, Type: "small"}, time.Duration(i%3)*time.Millisecond)
+				}
+				m.Size()
+			}
+		}()
+	}
+	wg.Wait()
+	time.Sleep(5 * time.Millisecond)
+
+	assert.Equal(t, m.ExactSize(), m.Size())
+	assert.Equal(t, len(m.Keys()), m.Size())
+	m.RemoveExpired()
+	assert.Equal(t, m.ApproxSize(), m.Size())
+	assert.Empty(t, m.Validate())
+}
@@ -0,0 +1,133 @@
+package indexedmap
+
+import "fmt"
+
+// Transaction collecting changes to be applied to the map together, see IndexedMap.Transaction.
+type Tx[T any] struct {
+	r   *IndexedMap[T]
+	ops []txOp[T]
+}
+
+// Staged transaction change
+type txOp[T any] struct {
+	key    string
+	orig   string
+	obj    T
+	remove bool
+}
+
+// Stage adding element to map by primary key.
+func (tx *Tx[T]) Put(key string, obj T) {
+	tx.ops = append(tx.ops, txOp[T]{key: tx.r.normalize(key), orig: key, obj: obj})
+}
+
+// Stage removing element from map by primary key.
+func (tx *Tx[T]) Remove(key string) {
+	tx.ops = append(tx.ops, txOp[T]{key: tx.r.normalize(key), remove: true})
+}
+
+// Get element by primary key, taking changes staged in this transaction into account.
+func (tx *Tx[T]) Get(key string) (T, bool) {
+	k := tx.r.normalize(key)
+	for i := len(tx.ops) - 1; i >= 0; i-- {
+		if op := tx.ops[i]; op.key == k {
+			var zero T
+			if op.remove {
+				return zero, false
+			}
+			return op.obj, true
+		}
+	}
+	return tx.r.Get(key)
+}
+
+// Run fn to stage changes and apply all of them at once if it returns nil.
+// If fn returns error nothing is applied and the error is returned.
+//
+// Guarantees:
+//   - Changes are applied under exclusive lock, so lookups (Get, GetByIndex and other index queries)
+//     observe either none or all of them, and secondary indexes are never seen partially updated.
+//   - Iteration which doesn't block writers (ForEach, All) may observe a partially applied transaction.
+//   - Transactions are atomic but not serializable: fn runs without locks,
+//     so elements read in fn may be changed by concurrent writers before commit.
+//     Committed changes overwrite them like regular Put and Remove do.
+//   - Changes are applied in staging order, change listeners are called after commit.
+//   - If a staged put would violate unique index, ErrUniqueViolation is returned and nothing is applied.
+//     Staged changes are checked in staging order, so a put may take unique value
+//     released by a remove or put of another key staged before it.
+//   - Returns ErrClosed without running fn if the map is closed.
+func (r *IndexedMap[T]) Transaction(fn func(tx *Tx[T]) error) error {
+	if r.closed.Load() {
+		return ErrClosed
+	}
+	tx := &Tx[T]{r: r}
+	if err := fn(tx); err != nil {
+		return err
+	}
+	events := make([]ChangeEvent[T], 0, len(tx.ops))
+	var err error
+	r.withLock(func() {
+		if err = r.checkUniqueTx(tx.ops); err != nil {
+			return
+		}
+		for _, op := range tx.ops {
+			if op.remove {
+				o, ok, dropped := r.remove(op.key)
+				events = append(events, dropped...)
+				if ok {
+					events = append(events, ChangeEvent[T]{Op: OpDelete, Key: op.key, Old: o})
+				}
+				continue
+			}
+			prev, loaded, dropped, err := r.put(op.key, op.orig, op.obj)
+			events = append(events, dropped...)
+			switch {
+			case err != nil:
+				// can't happen as unique indexes are checked and other writers are blocked
+			case loaded:
+				events = append(events, ChangeEvent[T]{Op: OpUpdate, Key: op.key, Old: prev, New: op.obj})
+			default:
+				events = append(events, ChangeEvent[T]{Op: OpInsert, Key: op.key, New: op.obj})
+			}
+		}
+	})
+	if err != nil {
+		return err
+	}
+	r.notify(events...)
+	r.evict()
+	return nil
+}
+
+// Check that applying ops in order doesn't violate unique indexes, must be called under exclusive lock.
+// Each staged put is checked against elements of keys not staged before it and against staged puts before it.
+func (r *IndexedMap[T]) checkUniqueTx(ops []txOp[T]) error {
+	if len(r.unique) == 0 {
+		return nil
+	}
+	type value struct{ name, v string }
+	staged := map[string]bool{}
+	owners := map[value]string{}
+	values := map[string][]value{}
+	for _, op := range ops {
+		staged[op.key] = true
+		for _, uv := range values[op.key] {
+			delete(owners, uv)
+		}
+		delete(values, op.key)
+		if op.remove {
+			continue
+		}
+		for _, name := range r.unique {
+			for _, v := range r.indexValues(name, &op.obj) {
+				uv := value{name, v}
+				if _, ok := owners[uv]; ok || r.uniqueTaken(name, v, func(k string) bool { return k != op.key && !staged[k] }) {
+					return fmt.Errorf("%w: %s=%s", ErrUniqueViolation, name, v)
+				}
+				owners[uv] = op.key
+				values[op.key] = append(values[op.key], uv)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,131 @@
+package indexedmap
+
+import (
+	"errors"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTransaction(t *testing.T) {
+	m := NewAnimalMap()
+
+	m.PutInt(1, Animal{Id: 1, Name: "Cat", Type: "small"})
+
+	err := m.Transaction(func(tx *Tx[Animal]) error {
+		tx.Put("2", Animal{Id: 2, Name: "Dog", Type: "small"})
+		tx.Remove("1")
+		_, ok := tx.Get("1")
+		assert.False(t, ok)
+		a, ok := tx.Get("2")
+		assert.True(t, ok)
+		assert.Equal(t, "Dog", a.Name)
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.False(t, m.ContainsKeyInt(1))
+	assert.True(t, m.ContainsKeyInt(2))
+	assert.Equal(t, 1, len(m.GetByIndex("Type", "small")))
+
+	fail := errors.New("fail")
+	err = m.Transaction(func(tx *Tx[Animal]) error {
+		tx.Put("3", Animal{Id: 3, Name: "Cow", Type: "big"})
+		tx.Remove("2")
+		return fail
+	})
+	assert.ErrorIs(t, err, fail)
+	assert.False(t, m.ContainsKeyInt(3))
+	assert.True(t, m.ContainsKeyInt(2))
+}
+
+func TestTransactionAtomicity(t *testing.T) {
+	m := NewAnimalMap()
+
+	// every transaction moves both elements to the same Type, readers must never see them split
+	for i := range 2 {
+		m.PutInt(i, Animal{Id: i, Type: "0"})
+	}
+
+	var stop atomic.Bool
+	var split atomic.Int32
+	var wg sync.WaitGroup
+	for range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for !stop.Load() {
+				for _, v := range m.GetIndexKeys("Type") {
+					if n := m.CountByIndex("Type", v); n == 1 {
+						split.Add(1)
+					}
+				}
+			}
+		}()
+	}
+
+	for i := range 1000 {
+		assert.NoError(t, m.Transaction(func(tx *Tx[Animal]) error {
+			tx.Put("0", Animal{Id: 0, Type: strconv.Itoa(i + 1)})
+			tx.Put("1", Animal{Id: 1, Type: strconv.Itoa(i + 1)})
+			return nil
+		}))
+	}
+	stop.Store(true)
+	wg.Wait()
+
+	assert.Equal(t, int32(0), split.Load())
+	assert.Equal(t, 2, len(m.GetByIndex("Type", "1000")))
+}
+
+func TestTransactionUnique(t *testing.T) {
+	m := NewUniquePersonMap()
+	m.Put("1", Person{Id: 1, LastName: "John", SSN: "111"})
+	m.Put("2", Person{Id: 2, LastName: "Doe", SSN: "222"})
+
+	// violation against stored element rejects the whole transaction
+	err := m.Transaction(func(tx *Tx[Person]) error {
+		tx.Remove("1")
+		tx.Put("3", Person{Id: 3, LastName: "Roe", SSN: "333"})
+		tx.Put("4", Person{Id: 4, LastName: "Poe", SSN: "222"})
+		return nil
+	})
+	assert.ErrorIs(t, err, ErrUniqueViolation)
+	assert.True(t, m.ContainsKey("1"))
+	assert.False(t, m.ContainsKey("3"))
+	assert.False(t, m.ContainsKey("4"))
+
+	// violation between staged puts
+	err = m.Transaction(func(tx *Tx[Person]) error {
+		tx.Put("3", Person{Id: 3, LastName: "Roe", SSN: "333"})
+		tx.Put("4", Person{Id: 4, LastName: "Poe", SSN: "333"})
+		return nil
+	})
+	assert.ErrorIs(t, err, ErrUniqueViolation)
+	assert.False(t, m.ContainsKey("3"))
+
+	// value released by earlier staged change can be taken, but not before it's released
+	err = m.Transaction(func(tx *Tx[Person]) error {
+		tx.Put("3", Person{Id: 3, LastName: "Roe", SSN: "111"})
+		tx.Remove("1")
+		return nil
+	})
+	assert.ErrorIs(t, err, ErrUniqueViolation)
+	assert.NoError(t, m.Transaction(func(tx *Tx[Person]) error {
+		tx.Remove("1")
+		tx.Put("3", Person{Id: 3, LastName: "Roe", SSN: "111"})
+		tx.Put("2", Person{Id: 2, LastName: "Doe", SSN: "444"})
+		tx.Put("4", Person{Id: 4, LastName: "Poe", SSN: "222"})
+		tx.Put("4", Person{Id: 4, LastName: "Poe", SSN: "555"})
+		tx.Put("5", Person{Id: 5, LastName: "Moe", SSN: "222"})
+		return nil
+	}))
+	assert.False(t, m.ContainsKey("1"))
+	assert.Equal(t, 4, m.Size())
+	assert.Equal(t, []int{3}, personIds(m.GetByIndex("SSN", "111")))
+	assert.Equal(t, []int{5}, personIds(m.GetByIndex("SSN", "222")))
+	assert.Equal(t, []int{4}, personIds(m.GetByIndex("SSN", "555")))
+	assert.Empty(t, m.Validate())
+}
@@ -0,0 +1,178 @@
+package indexedmap
+
+import (
+	"cmp"
+	"sync"
+
+	"github.com/google/btree"
+)
+
+// Secondary index with values of type V kept in their natural order, e.g. numbers or times,
+// so they are range queried without converting them to strings.
+// Created with NewTypedIndex for a map, it's maintained by change listener of the map,
+// so like string indexes it's eventually consistent with primary index.
+// Unlike string indexes, zero values are indexed too.
+// Close index which is not needed anymore, so the map stops maintaining it.
+type TypedIndex[T any, V any] struct {
+	r    *IndexedMap[T]
+	fn   func(obj *T) V
+	less func(a, b V) bool
+
+	// Unregisters change listener maintaining the index
+	unsubscribe func()
+
+	mu     sync.RWMutex
+	tree   *btree.BTreeG[typedEntry[V]]
+	values map[string]V
+	closed bool
+}
+
+// Index value and primary key of indexed element
+type typedEntry[V any] struct {
+	value V
+	key   string
+}
+
+// Create typed index of map for ordered values like numbers and strings.
+// Index is built from elements already stored in the map and kept up to date on every change.
+func NewTypedIndex[T any, V cmp.Ordered](r *IndexedMap[T], fn func(obj *T) V) *TypedIndex[T, V] {
+	return NewTypedIndexFunc(r, fn, cmp.Less[V])
+}
+
+// Create typed index of map for values ordered by less, e.g. time.Time with Before.
+func NewTypedIndexFunc[T any, V any](r *IndexedMap[T], fn func(obj *T) V, less func(a, b V) bool) *TypedIndex[T, V] {
+	x := &TypedIndex[T, V]{
+		r:    r,
+		fn:   fn,
+		less: less,
+		tree: btree.NewG(32, func(a, b typedEntry[V]) bool {
+			if less(a.value, b.value) {
+				return true
+			}
+			if less(b.value, a.value) {
+				return false
+			}
+			return a.key < b.key
+		}),
+		values: map[string]V{},
+	}
+	// register listener first, so elements put during the build are not missed
+	x.unsubscribe = r.OnChange(func(e ChangeEvent[T]) {
+		if e.Op == OpClear {
+			x.refreshAll()
+		} else {
+			x.refresh(e.Key)
+		}
+	})
+	r.primary.Range(func(k string, _ any) bool {
+		x.refresh(k)
+		return true
+	})
+	return x
+}
+
+// Reindex element by current state of primary index.
+// Change events of the same key may be delivered out of order, reading primary index
+// instead of event payload makes the last delivered event always leave the index correct.
+func (x *TypedIndex[T, V]) refresh(key string) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	x.refreshLocked(key)
+}
+
+// Reindex all indexed elements after Clear, dropping the removed ones.
+// Elements put after Clear are refreshed again, which leaves them indexed as well.
+func (x *TypedIndex[T, V]) refreshAll() {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	keys := make([]string, 0, len(x.values))
+	for key := range x.values {
+		keys = append(keys, key)
+	}
+	for _, key := range keys {
+		x.refreshLocked(key)
+	}
+}
+
+func (x *TypedIndex[T, V]) refreshLocked(key string) {
+	if x.closed {
+		// event delivered concurrently with Close
+		return
+	}
+	if v, ok := x.values[key]; ok {
+		x.tree.Delete(typedEntry[V]{v, key})
+		delete(x.values, key)
+	}
+	obj, ok := x.r.lookup(key)
+	if !ok {
+		return
+	}
+	v := x.fn(&obj)
+	x.tree.ReplaceOrInsert(typedEntry[V]{v, key})
+	x.values[key] = v
+}
+
+// Find all elements by index value.
+func (x *TypedIndex[T, V]) Get(v V) []T {
+	return x.GetRange(v, v)
+}
+
+// Find all elements with index values in [lo, hi] range, ordered by index value.
+// Elements are read from primary index, the ones removed, expired or changed since indexing are skipped.
+func (x *TypedIndex[T, V]) GetRange(lo, hi V) []T {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	result := []T{}
+	x.tree.AscendGreaterOrEqual(typedEntry[V]{value: lo}, func(e typedEntry[V]) bool {
+		if x.less(hi, e.value) {
+			return false
+		}
+		if obj, ok := x.r.lookup(e.key); ok && x.equal(x.fn(&obj), e.value) {
+			result = append(result, obj)
+		}
+		return true
+	})
+	return result
+}
+
+// Get smallest and largest index values, false if index is empty.
+// Values of elements removed, expired or changed since indexing are skipped like in GetRange.
+func (x *TypedIndex[T, V]) Bounds() (V, V, bool) {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	var lo, hi V
+	found := false
+	x.tree.Ascend(func(e typedEntry[V]) bool {
+		lo, found = e.value, x.current(e)
+		return !found
+	})
+	if !found {
+		return lo, hi, false
+	}
+	x.tree.Descend(func(e typedEntry[V]) bool {
+		hi = e.value
+		return !x.current(e)
+	})
+	return lo, hi, true
+}
+
+// Stop maintaining the index and drop its entries, index is empty afterwards.
+// Closing already closed index does nothing.
+func (x *TypedIndex[T, V]) Close() {
+	x.unsubscribe()
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	x.tree.Clear(false)
+	clear(x.values)
+	x.closed = true
+}
+
+// Check if entry still matches element in primary index.
+func (x *TypedIndex[T, V]) current(e typedEntry[V]) bool {
+	obj, ok := x.r.lookup(e.key)
+	return ok && x.equal(x.fn(&obj), e.value)
+}
+
+func (x *TypedIndex[T, V]) equal(a, b V) bool {
+	return !x.less(a, b) && !x.less(b, a)
+}
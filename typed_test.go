@@ -0,0 +1,82 @@
+package indexedmap
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func animalIds(list []Animal) []int {
+	ids := []int{}
+	for _, a := range list {
+		ids = append(ids, a.Id)
+	}
+	return ids
+}
+
+func TestTypedIndex(t *testing.T) {
+	m := NewAnimalMap()
+	m.PutInt(1, Animal{Id: 1, Name: "Cat", NumType: 4})
+	m.PutInt(2, Animal{Id: 2, Name: "Dog", NumType: 12})
+
+	idx := NewTypedIndex(m, func(a *Animal) int { return a.NumType })
+
+	m.PutInt(3, Animal{Id: 3, Name: "Cow", NumType: 2})
+	m.PutInt(4, Animal{Id: 4, Name: "Fish"})
+
+	assert.Equal(t, []int{3, 1}, animalIds(idx.GetRange(2, 10)))
+	assert.Equal(t, []int{4}, animalIds(idx.Get(0)))
+	assert.Equal(t, []Animal{}, idx.Get(5))
+
+	lo, hi, ok := idx.Bounds()
+	assert.True(t, ok)
+	assert.Equal(t, 0, lo)
+	assert.Equal(t, 12, hi)
+
+	m.PutInt(2, Animal{Id: 2, Name: "Dog", NumType: 3})
+	m.RemoveInt(3)
+	assert.Equal(t, []int{2, 1}, animalIds(idx.GetRange(2, 10)))
+
+	m.Clear()
+	assert.Equal(t, []Animal{}, idx.GetRange(0, 100))
+	_, _, ok = idx.Bounds()
+	assert.False(t, ok)
+	assert.Empty(t, idx.values)
+	m.PutInt(5, Animal{Id: 5, Name: "Owl", NumType: 7})
+	assert.Equal(t, []int{5}, animalIds(idx.GetRange(0, 100)))
+
+	m.PutWithTTL("6", Animal{Id: 6, Name: "Fly", NumType: 100}, time.Millisecond)
+	time.Sleep(5 * time.Millisecond)
+	lo, hi, ok = idx.Bounds()
+	assert.True(t, ok)
+	assert.Equal(t, 7, lo)
+	assert.Equal(t, 7, hi)
+
+	idx.Close()
+	idx.Close()
+	m.PutInt(7, Animal{Id: 7, Name: "Ant", NumType: 1})
+	assert.Equal(t, []Animal{}, idx.GetRange(0, 100))
+	_, _, ok = idx.Bounds()
+	assert.False(t, ok)
+	assert.Empty(t, *m.listeners.Load())
+}
+
+func TestTypedIndexTime(t *testing.T) {
+	type Event struct {
+		Name string
+		At   time.Time
+	}
+	m := NewIndexedMap(map[string]IndexFunc[Event]{})
+	idx := NewTypedIndexFunc(m, func(e *Event) time.Time { return e.At }, time.Time.Before)
+
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	m.Put("a", Event{Name: "a", At: start.Add(2 * time.Hour)})
+	m.Put("b", Event{Name: "b", At: start})
+	m.Put("c", Event{Name: "c", At: start.Add(5 * time.Hour)})
+
+	list := idx.GetRange(start, start.Add(3*time.Hour))
+	assert.Equal(t, 2, len(list))
+	assert.Equal(t, "b", list[0].Name)
+	assert.Equal(t, "a", list[1].Name)
+}
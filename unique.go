@@ -0,0 +1,113 @@
+package indexedmap
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Returned when element can't be stored because another element has the same value of unique index.
+var ErrUniqueViolation = errors.New("indexedmap: unique index violation")
+
+// Add element to map by primary key like Put, reporting rejected writes.
+// Returns ErrUniqueViolation if element with another key has the same value of unique index,
+// the map is not changed then. Keeping the same unique value on update of the same key is allowed.
+// Returns ErrClosed if the map is closed.
+func (r *IndexedMap[T]) PutErr(k string, obj T) error {
+	if r.closed.Load() {
+		return ErrClosed
+	}
+	_, _, err := r.putKey(r.normalize(k), k, obj)
+	return err
+}
+
+// Add element to map only if primary key is not present yet like PutIfAbsent, reporting rejected writes.
+// Returns ErrUniqueViolation with zero value and false if element with another key has the same value of unique index,
+// the map is not changed then. Returns ErrClosed if the map is closed.
+func (r *IndexedMap[T]) PutIfAbsentErr(k string, obj T) (T, bool, error) {
+	if r.closed.Load() {
+		var zero T
+		return zero, false, ErrClosed
+	}
+	return r.putIfAbsent(k, obj)
+}
+
+// Check unique indexes for element and keep them locked until unlockUnique on success.
+// Must be called under primary key lock, so the lock order is always key lock first.
+func (r *IndexedMap[T]) lockUnique(key string, obj *T) error {
+	if len(r.unique) == 0 {
+		return nil
+	}
+	r.uniqueMu.Lock()
+	locked := false
+	defer func() {
+		// released on violation and if index function panics
+		if !locked {
+			r.uniqueMu.Unlock()
+		}
+	}()
+	for _, name := range r.unique {
+		for _, v := range r.indexValues(name, obj) {
+			if r.uniqueTaken(name, v, func(k string) bool { return k != key }) {
+				return fmt.Errorf("%w: %s=%s", ErrUniqueViolation, name, v)
+			}
+		}
+	}
+	locked = true
+	return nil
+}
+
+// Check if value of unique index is held by element whose key is accepted by other.
+// Expired elements don't hold values, like they are absent for lookups.
+func (r *IndexedMap[T]) uniqueTaken(name string, v string, other func(key string) bool) bool {
+	m, ok := r.findIndexMapList(name, v)
+	if !ok {
+		return false
+	}
+	taken := false
+	m.Range(func(k string, _ any) bool {
+		taken = other(k) && !r.expired(k)
+		return !taken
+	})
+	return taken
+}
+
+func (r *IndexedMap[T]) unlockUnique() {
+	if len(r.unique) > 0 {
+		r.uniqueMu.Unlock()
+	}
+}
+
+// Get primary index element or create it with valueFn if it's missing or expired.
+// Returns the element and true if it was already present, and OpDelete event of expired element, see compute.
+// If valueFn returns false nothing is stored and nil is returned.
+func (r *IndexedMap[T]) loadOrCompute(key string, valueFn func() (any, bool)) (any, bool, []ChangeEvent[T]) {
+	if v, ok := r.primary.Load(key); ok && !r.expired(key) {
+		return v, true, nil
+	}
+	var actual any
+	var loaded bool
+	dropped := r.compute(key, func(old any, ok bool) (any, bool) {
+		if ok {
+			actual, loaded = old, true
+			return old, false
+		}
+		v, store := valueFn()
+		if !store {
+			return nil, true
+		}
+		actual = v
+		return v, false
+	})
+	return actual, loaded, dropped
+}
+
+// Index new element if it doesn't violate unique indexes, returns value to store or ErrUniqueViolation.
+// Must be called under primary key lock.
+func (r *IndexedMap[T]) insertUnique(key string, orig string, obj *T) (any, error) {
+	if err := r.lockUnique(key, obj); err != nil {
+		return nil, err
+	}
+	defer r.unlockUnique()
+	r.insert(key, orig, obj)
+	return r.box(obj), nil
+}
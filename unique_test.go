@@ -0,0 +1,96 @@
+package indexedmap
+
+import (
+	"strconv"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func NewUniquePersonMap() *IndexedMap[Person] {
+	return NewIndexedMapWithOptions(map[string]IndexFunc[Person]{
+		"SSN": func(p *Person) string {
+			return p.SSN
+		},
+		"LastName": func(p *Person) string {
+			return p.LastName
+		},
+	}, Options[Person]{UniqueIndexes: []string{"SSN"}})
+}
+
+func TestUniqueIndex(t *testing.T) {
+	m := NewUniquePersonMap()
+
+	assert.NoError(t, m.PutErr("1", Person{Id: 1, LastName: "John", SSN: "111"}))
+	assert.NoError(t, m.PutErr("2", Person{Id: 2, LastName: "John", SSN: "222"}))
+	assert.NoError(t, m.PutErr("1", Person{Id: 1, LastName: "Johnny", SSN: "111"}))
+
+	err := m.PutErr("3", Person{Id: 3, LastName: "Jane", SSN: "111"})
+	assert.ErrorIs(t, err, ErrUniqueViolation)
+	assert.False(t, m.ContainsKey("3"))
+	assert.Equal(t, 1, len(m.GetByIndex("SSN", "111")))
+
+	assert.ErrorIs(t, m.PutErr("2", Person{Id: 2, LastName: "John", SSN: "111"}), ErrUniqueViolation)
+	p, _ := m.Get("2")
+	assert.Equal(t, "222", p.SSN)
+
+	m.Put("3", Person{Id: 3, SSN: "222"})
+	assert.False(t, m.ContainsKey("3"))
+
+	_, loaded := m.PutIfAbsent("3", Person{Id: 3, SSN: "222"})
+	assert.True(t, loaded)
+	assert.False(t, m.ContainsKey("3"))
+	_, loaded, err = m.PutIfAbsentErr("3", Person{Id: 3, SSN: "222"})
+	assert.False(t, loaded)
+	assert.ErrorIs(t, err, ErrUniqueViolation)
+	p, loaded, err = m.PutIfAbsentErr("3", Person{Id: 3, SSN: "333"})
+	assert.False(t, loaded)
+	assert.NoError(t, err)
+	assert.Equal(t, "333", p.SSN)
+	p, loaded, err = m.PutIfAbsentErr("3", Person{Id: 3, SSN: "444"})
+	assert.True(t, loaded)
+	assert.NoError(t, err)
+	assert.Equal(t, "333", p.SSN)
+	m.Remove("3")
+
+	assert.False(t, m.Update("1", func(p *Person) bool {
+		p.SSN = "222"
+		return true
+	}))
+
+	m.Remove("2")
+	assert.NoError(t, m.PutErr("3", Person{Id: 3, SSN: "222"}))
+	assert.Equal(t, 2, m.Size())
+
+	m.RemoveIndex("SSN")
+	m.AddIndex("SSN", func(p *Person) string {
+		return p.SSN
+	})
+	assert.NoError(t, m.PutErr("4", Person{Id: 4, SSN: "222"}))
+}
+
+func TestConcurrentUniqueIndex(t *testing.T) {
+	m := NewUniquePersonMap()
+
+	var stored atomic.Int64
+	workers := 8
+	ch := make(chan int, workers)
+	for w := range workers {
+		go func() {
+			for i := range 100 {
+				if m.PutErr(strconv.Itoa(w*100+i), Person{Id: i, SSN: strconv.Itoa(i)}) == nil {
+					stored.Add(1)
+				}
+			}
+			ch <- 1
+		}()
+	}
+	waitChan(ch, workers)
+
+	assert.Equal(t, int64(100), stored.Load())
+	assert.Equal(t, 100, m.Size())
+	for i := range 100 {
+		assert.Equal(t, 1, len(m.GetByIndex("SSN", strconv.Itoa(i))))
+	}
+}
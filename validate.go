@@ -0,0 +1,59 @@
+package indexedmap
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/puzpuzpuz/xsync/v3"
+)
+
+// Reported by Validate for every mismatch between primary and secondary indexes.
+var ErrIndexDrift = errors.New("indexedmap: index drift")
+
+// Check that secondary indexes match primary index.
+// Every key of every index value must exist in primary index and compute to that value,
+// and every element of primary index must be found under each of its non-empty index values.
+// Returns nil if indexes are consistent, otherwise errors wrapping ErrIndexDrift.
+// Diagnostic tool: writes running concurrently are reported as drift, so call it on a quiesced map.
+func (r *IndexedMap[T]) Validate() []error {
+	t := r.mu.RLock()
+	defer r.mu.RUnlock(t)
+	var errs []error
+	for _, name := range r.indexNames() {
+		r.secondary[name].Range(func(value string, v any) bool {
+			v.(*xsync.Map).Range(func(key string, _ any) bool {
+				obj, ok := r.primary.Load(key)
+				if !ok {
+					errs = append(errs, fmt.Errorf("%w: %s=%s has missing key %s", ErrIndexDrift, name, value, key))
+				} else if !slices.Contains(r.indexValues(name, r.pointer(obj)), value) {
+					errs = append(errs, fmt.Errorf("%w: %s=%s has key %s of another value", ErrIndexDrift, name, value, key))
+				}
+				return true
+			})
+			return true
+		})
+	}
+	n := 0
+	r.primary.Range(func(key string, obj any) bool {
+		n++
+		for _, name := range r.indexNames() {
+			for _, value := range r.indexValues(name, r.pointer(obj)) {
+				if m, ok := r.findIndexMapList(name, value); !ok || !containsKey(m, key) {
+					errs = append(errs, fmt.Errorf("%w: key %s is missing in %s=%s", ErrIndexDrift, key, name, value))
+				}
+			}
+		}
+		return true
+	})
+	// expired elements not removed yet are still in primary index and counted by ApproxSize, unlike Size
+	if size := r.ApproxSize(); size != n {
+		errs = append(errs, fmt.Errorf("%w: size is %d, primary index has %d elements", ErrIndexDrift, size, n))
+	}
+	return errs
+}
+
+func containsKey(m *xsync.Map, key string) bool {
+	_, ok := m.Load(key)
+	return ok
+}
@@ -0,0 +1,59 @@
+package indexedmap
+
+import (
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidate(t *testing.T) {
+	m := NewTaggedPersonMap()
+	assert.Nil(t, m.Validate())
+
+	m.PutInt(1, Person{Id: 1, LastName: "Smith", Tags: []string{"admin", "dev"}})
+	m.PutInt(2, Person{Id: 2, LastName: "Doe", Tags: []string{"dev"}})
+	m.PutInt(3, Person{Id: 3, LastName: "Doe"})
+	m.PutInt(1, Person{Id: 1, LastName: "Doe", Tags: []string{"ops"}})
+	m.RemoveInt(3)
+	assert.Nil(t, m.Validate())
+
+	m.PutWithTTL("5", Person{Id: 5, LastName: "Brown", Tags: []string{"dev"}}, time.Millisecond)
+	time.Sleep(5 * time.Millisecond)
+	assert.Nil(t, m.Validate())
+	assert.Equal(t, 1, m.RemoveExpired())
+
+	m.GetByIndexUnderlyingMap("Tag", "dev").Delete("2")
+	m.GetByIndexUnderlyingMap("LastName", "doe").Store("4", struct{}{})
+	errs := m.Validate()
+	assert.Equal(t, 2, len(errs))
+	for _, err := range errs {
+		assert.ErrorIs(t, err, ErrIndexDrift)
+	}
+}
+
+func TestValidateAfterChurn(t *testing.T) {
+	m := NewTaggedPersonMap()
+
+	var wg sync.WaitGroup
+	for w := range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range 5000 {
+				id := (i*7 + w) % 200
+				if i%3 == 0 {
+					m.RemoveInt(id)
+				} else {
+					m.PutInt(id, Person{Id: id, LastName: "L" + strconv.Itoa(i%5), Tags: []string{"t" + strconv.Itoa(i%4)}})
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Nil(t, m.Validate())
+	assert.Equal(t, m.ExactSize(), m.Size())
+}